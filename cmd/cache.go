@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+var cacheSettings = struct {
+	json      bool
+	olderThan string
+	yes       bool
+}{}
+
+// downloadCache returns the shared download cache configured in the config
+func downloadCache() *pkg.DownloadCache {
+	conf := gomconfig.Get()
+	if conf == nil {
+		return pkg.NewDownloadCache(filepath.Join(fsutil.DefaultDataDir, "cache"), 0)
+	}
+	return pkg.NewDownloadCache(conf.DownloadCacheDir(), conf.CacheMaxSize())
+}
+
+// parseAge parses a duration like time.ParseDuration, also accepting days and weeks like 30d or 2w.
+// Negative ages are rejected, they would match every archive.
+func parseAge(s string) (time.Duration, error) {
+	age, err := parseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if age < 0 {
+		return 0, errors.Errorf("invalid age %q, it can't be negative", s)
+	}
+	return age, nil
+}
+
+// parseDuration is time.ParseDuration with days and weeks
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			num, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, errors.Errorf("invalid age %q", s)
+			}
+			return time.Duration(num * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+func renderCacheEntries(entries []pkg.CacheEntry) error {
+	var total int64
+	data := pterm.TableData{{"Archive", "Size", "Last used", "SHA-256"}}
+	for _, e := range entries {
+		total += e.Size
+		data = append(data, []string{e.Filename, formatSize(e.Size), e.LastUsed.Format(time.DateTime), e.Sha256[:12]})
+	}
+	if err := pterm.DefaultTable.WithHasHeader().WithData(data).Render(); err != nil {
+		return err
+	}
+	pterm.Info.Printfln("%d archives, %s total", len(entries), formatSize(total))
+	return nil
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the shared download cache",
+	Long: `Release archives are kept in a cache keyed by their SHA-256 after they are downloaded and verified,
+so installing the same release again doesn't fetch it twice.
+The cache is trimmed to cache_max_size from the config, least recently used archives first.`,
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List the cached archives",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache := downloadCache()
+		entries, err := cache.Entries()
+		if err != nil {
+			return err
+		}
+		if cacheSettings.json {
+			useOutput(outputJSON)
+		}
+		return render("cache_list", entries, func() error {
+			if len(entries) == 0 {
+				pterm.Info.Printfln("The cache in %s is empty", cache.Dir)
+				return nil
+			}
+			return renderCacheEntries(entries)
+		})
+	},
+}
+
+// cacheRemoveResult is the document of cache verify and cache clean
+type cacheRemoveResult struct {
+	Dir     string           `json:"dir"`
+	Removed []pkg.CacheEntry `json:"removed"`
+	Freed   int64            `json:"freed"`
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the cached archives against their checksums and remove corrupt ones",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache := downloadCache()
+		bad, err := cache.Verify()
+		if err != nil {
+			return err
+		}
+		res := cacheRemoveResult{Dir: cache.Dir, Removed: []pkg.CacheEntry{}}
+		for _, e := range bad {
+			if err := cache.Remove(e); err != nil {
+				return err
+			}
+			res.Removed = append(res.Removed, e)
+			res.Freed += e.Size
+		}
+		err = render("cache_verify", res, func() error {
+			if len(bad) == 0 {
+				pterm.Success.Println("All cached archives are intact")
+			}
+			for _, e := range bad {
+				pterm.Warning.Printfln("Removed corrupt %s", e.Filename)
+			}
+			return nil
+		})
+		if err != nil || len(bad) == 0 {
+			return err
+		}
+		if structuredOutput() {
+			// the document already lists the corrupt archives, an error document would be a second one
+			os.Exit(1)
+		}
+		return errors.Errorf("%d cached archives were corrupt", len(bad))
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:     "clean",
+	Short:   "Remove cached archives",
+	Example: "go-manager cache clean\ngo-manager cache clean --older-than 30d",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache := downloadCache()
+		entries, err := cache.Entries()
+		if cacheSettings.olderThan != "" {
+			age, ageErr := parseAge(cacheSettings.olderThan)
+			if ageErr != nil {
+				return ageErr
+			}
+			entries, err = cache.Older(age)
+		}
+		if err != nil {
+			return err
+		}
+		res := cacheRemoveResult{Dir: cache.Dir, Removed: []pkg.CacheEntry{}}
+		table := func() error {
+			if len(res.Removed) > 0 {
+				pterm.Success.Printfln("Freed %s", formatSize(res.Freed))
+			}
+			return nil
+		}
+		if len(entries) == 0 {
+			pterm.Info.Println("Nothing to clean")
+			return render("cache_clean", res, table)
+		}
+		// with structured output the archives and the prompt go to stderr
+		if err := renderCacheEntries(entries); err != nil {
+			return err
+		}
+		if !cacheSettings.yes {
+			ok, err := pterm.DefaultInteractiveConfirm.WithDefaultText("Remove them?").Show()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return render("cache_clean", res, table)
+			}
+		}
+		for _, e := range entries {
+			if err := cache.Remove(e); err != nil {
+				return err
+			}
+			res.Removed = append(res.Removed, e)
+			res.Freed += e.Size
+		}
+		return render("cache_clean", res, table)
+	},
+}
+
+func init() {
+	cacheLsCmd.Flags().BoolVar(&cacheSettings.json, "json", false, "shorthand for --output json")
+	cacheCleanCmd.Flags().StringVar(&cacheSettings.olderThan, "older-than", "", "only remove archives unused for longer than this, like 72h or 30d")
+	cacheCleanCmd.Flags().BoolVarP(&cacheSettings.yes, "yes", "y", false, "don't ask for confirmation")
+	cacheCmd.AddCommand(cacheLsCmd, cacheVerifyCmd, cacheCleanCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{age: "72h", want: 72 * time.Hour},
+		{age: "90m", want: 90 * time.Minute},
+		{age: "30d", want: 30 * 24 * time.Hour},
+		{age: "1.5d", want: 36 * time.Hour},
+		{age: "2w", want: 14 * 24 * time.Hour},
+		{age: "0", want: 0},
+		{age: "", wantErr: true},
+		{age: "d", wantErr: true},
+		{age: "xw", wantErr: true},
+		{age: "1y", wantErr: true},
+		{age: "-1d", wantErr: true},
+		{age: "-2h", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.age, func(t *testing.T) {
+			got, err := parseAge(tt.age)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAge(%q) error = %v, wantErr %v", tt.age, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseAge(%q) = %s, want %s", tt.age, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatSize(tt.size); got != tt.want {
+				t.Errorf("formatSize(%d) = %q, want %q", tt.size, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+)
+
+type configValue struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// configValues returns the settings of the loaded config by their keys in the config file
+func configValues() (map[string]interface{}, error) {
+	conf := gomconfig.Get()
+	if conf == nil {
+		return nil, errors.New("config not loaded")
+	}
+	return conf.Values(), nil
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the go-manager config",
+}
+
+var configGetCmd = &cobra.Command{
+	Use:     "get [key]",
+	Short:   "Print the config, or a single setting of it",
+	Example: "go-manager config get\ngo-manager config get envs_dir",
+	Args:    cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values, err := configValues()
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			value, ok := values[args[0]]
+			if !ok {
+				return errors.Errorf("unknown config key %q", args[0])
+			}
+			return render("config_value", configValue{Key: args[0], Value: value}, func() error {
+				pterm.Println(fmt.Sprint(value))
+				return nil
+			})
+		}
+		return render("config", values, func() error {
+			keys := make([]string, 0, len(values))
+			for k := range values {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			data := pterm.TableData{{"Key", "Value"}}
+			for _, k := range keys {
+				data = append(data, []string{k, fmt.Sprint(values[k])})
+			}
+			return pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+		})
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+// Where the version in use was chosen
+const (
+	sourceProject = "project"
+	sourceEnv     = "env"
+	sourceConfig  = "config"
+	sourceArgs    = "args"
+	sourceGopath  = "gopath_env"
+)
+
+var errNoCurrent = errors.New("no go version pinned by the project, set in " + versionEnv + ", bound to the active env or set with use")
+
+var currentExplain bool
+
+type currentResult struct {
+	Version   string `json:"version"`
+	Query     string `json:"query"`
+	Source    string `json:"source"`
+	File      string `json:"file,omitempty"`
+	Directive string `json:"directive,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Installed bool   `json:"installed"`
+	Path      string `json:"path,omitempty"`
+	// Minimum is the minimum version pinned by the project the version satisfies
+	Minimum *pkg.Pin `json:"minimum,omitempty"`
+}
+
+// versionEnv overrides the current version from the config when no project pins one
+const versionEnv = "GOM_VERSION"
+
+// selectQuery returns the version query in effect in dir, from the first of: the project files above dir,
+// the GOM_VERSION environment variable, the version of the active env and the current version in the config.
+// A project pinning only a minimum version, like the go line of go.mod, is satisfied by the others when they're new enough.
+func selectQuery(dir string) (*currentResult, error) {
+	pin, err := pkg.FindPin(dir)
+	if err != nil {
+		return nil, err
+	}
+	fallback := fallbackQuery()
+	if pin == nil {
+		if fallback == nil {
+			return nil, errNoCurrent
+		}
+		return fallback, nil
+	}
+	if fallback != nil && fallback.satisfies(pin) {
+		fallback.Minimum = pin
+		return fallback, nil
+	}
+	return &currentResult{Query: pin.Query, Source: sourceProject, File: pin.File, Directive: pin.Directive}, nil
+}
+
+// fallbackQuery returns the version query in effect outside of projects, or nil if there's none
+func fallbackQuery() *currentResult {
+	named := activeEnv()
+	switch {
+	case os.Getenv(versionEnv) != "":
+		return &currentResult{Query: os.Getenv(versionEnv), Source: sourceEnv}
+	case named != nil && named.Go != "":
+		return &currentResult{Query: named.Go, Source: sourceGopath, Env: named.EnvName}
+	case gomconfig.Get() != nil && gomconfig.Get().Current != nil:
+		return &currentResult{Query: gomconfig.Get().Current.String(), Source: sourceConfig}
+	}
+	return nil
+}
+
+// satisfies reports whether the installed version r resolves to may be used for the project pin
+func (r *currentResult) satisfies(pin *pkg.Pin) bool {
+	if !pin.Minimum {
+		return false
+	}
+	resolved := *r
+	if err := resolved.resolveInstalled(); err != nil {
+		return false
+	}
+	return pin.SatisfiedBy(resolved.Version)
+}
+
+// resolveInstalled resolves the query of res against the installed toolchains
+func (r *currentResult) resolveInstalled() error {
+	installed, err := pkg.InstalledVersions(envsDir())
+	if err != nil {
+		return err
+	}
+	version, err := installed.Resolve(r.Query)
+	if err != nil {
+		return err
+	}
+	r.Version, r.Installed, r.Path = version.Version, true, pkg.InstallDir(envsDir(), version.Version)
+	return nil
+}
+
+// selectVersion returns the go version to use in dir, see selectQuery.
+// The query is resolved against the installed toolchains first and the version index only if none of them matches.
+func selectVersion(ctx context.Context, dir string) (*currentResult, error) {
+	res, err := selectQuery(dir)
+	if err != nil {
+		return nil, err
+	}
+	err = res.resolveInstalled()
+	if errors.Is(err, pkg.ErrNoMatch) {
+		versions, err := getVersions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		version, err := versions.Resolve(res.Query)
+		if err != nil {
+			return nil, errors.Wrapf(err, "no go version matches %s set by %s", res.Query, res.origin())
+		}
+		res.Version = version.Version
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "invalid version set by %s", res.origin())
+	}
+	return res, nil
+}
+
+// origin describes where the version was chosen
+func (r *currentResult) origin() string {
+	switch {
+	case r.Directive != "":
+		return "the " + r.Directive + " line of " + r.File
+	case r.File != "":
+		return r.File
+	case r.Source == sourceEnv:
+		return "the " + versionEnv + " environment variable"
+	case r.Source == sourceArgs:
+		return "the command line"
+	case r.Source == sourceGopath:
+		return "the env " + r.Env
+	default:
+		return "the current version in the config"
+	}
+}
+
+var currentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Print the Go version in use in the working directory",
+	Long: "Print the Go version in use in the working directory.\n" +
+		"The version is pinned by the first " + pkg.GoVersionFile + ", " + pkg.GomFile + ", " + pkg.GoWorkFile + " or " + pkg.GoModFile +
+		" found walking up from the working directory, the toolchain line of go.work and go.mod wins over their go line. " +
+		"The go line is only a minimum, the version in use outside of the project is kept when it satisfies it. " +
+		"Without a pin the version in the " + versionEnv + " environment variable, or else the one bound to the active env, or else the one set with use, is in use.",
+	Example: "go-manager current\ngo-manager current --explain",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		res, err := selectVersion(cmd.Context(), wd)
+		if err != nil {
+			return err
+		}
+		return render("current", res, func() error {
+			if !currentExplain {
+				pterm.Fprintln(os.Stdout, res.Version)
+				return nil
+			}
+			pterm.Info.Printfln("%s is chosen by %s (%s)", res.Version, res.origin(), res.Query)
+			if res.Minimum != nil {
+				pterm.Info.Printfln("it satisfies %s required by the %s line of %s", res.Minimum.Query, res.Minimum.Directive, res.Minimum.File)
+			}
+			if res.Installed {
+				pterm.Info.Printfln("installed in %s", res.Path)
+			} else {
+				pterm.Warning.Printfln("%s isn't installed, install it with: go-manager install %s", res.Version, res.Version)
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	currentCmd.Flags().BoolVar(&currentExplain, "explain", false, "show which file or setting chose the version")
+	rootCmd.AddCommand(currentCmd)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+var envSettings struct {
+	shell string
+	unset bool
+}
+
+type envResult struct {
+	Shell  string `json:"shell"`
+	Script string `json:"script"`
+	*pkg.ShellEnv
+}
+
+// defaultShell guesses the shell go-manager runs in from $SHELL, PowerShell on Windows
+func defaultShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	if _, err := pkg.GetShell(filepath.Base(os.Getenv("SHELL"))); err == nil {
+		return filepath.Base(os.Getenv("SHELL"))
+	}
+	return "sh"
+}
+
+// gopath returns the GOPATH in use: the one of the active env, or else the go default of ~/go unless it's set
+func gopath() string {
+	if env := activeEnv(); env != nil {
+		return env.Dir
+	}
+	if p := os.Getenv("GOPATH"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "go")
+}
+
+// shellEnv returns the environment of the active toolchain: the shims and its bin on PATH, GOROOT and GOPATH,
+// with the GOBIN of the active env on PATH too. The toolchain is found through the current link,
+// so the environment stays valid when use switches it.
+func shellEnv() *pkg.ShellEnv {
+	goroot := pkg.CurrentLink(fsutil.DefaultDataDir)
+	env := &pkg.ShellEnv{Path: []string{pkg.AliasDir, filepath.Join(goroot, "bin")}}
+	// a GOROOT that doesn't exist breaks every go on PATH, leave it alone until a toolchain is in use
+	if _, err := os.Stat(goroot); err == nil {
+		env.Vars = append(env.Vars, pkg.EnvVar{Name: "GOROOT", Value: goroot})
+	}
+	if named := activeEnv(); named != nil {
+		env.Path = append([]string{pkg.AliasDir, named.BinDir}, env.Path[1:]...)
+		env.Vars = append(env.Vars, pkg.EnvVar{Name: "GOPATH", Value: named.Dir}, pkg.EnvVar{Name: "GOBIN", Value: named.BinDir})
+		return env
+	}
+	env.Vars = append(env.Vars, pkg.EnvVar{Name: "GOPATH", Value: gopath()})
+	return env
+}
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print the shell commands setting up the environment of the active toolchain",
+	Long: "Print the shell commands putting the shims and the active toolchain on PATH and setting GOROOT and GOPATH.\n" +
+		"With --unset the commands undoing them are printed instead. " +
+		"The subcommands manage named environments, each with its own GOPATH, GOBIN and optionally Go version.",
+	Example:     "eval \"$(go-manager env --shell bash)\"\ngo-manager env --shell fish | source\ngo-manager env --shell powershell | Out-String | Invoke-Expression",
+	Annotations: map[string]string{stdoutAnnotation: ""},
+	Args:        cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sh, err := pkg.GetShell(envSettings.shell)
+		if err != nil {
+			return err
+		}
+		env := shellEnv()
+		script := env.Script(sh)
+		if envSettings.unset {
+			script = env.UnsetScript(sh)
+		}
+		return render("env", envResult{Shell: sh.Name(), Script: script, ShellEnv: env}, func() error {
+			_, err := fmt.Fprint(os.Stdout, script)
+			return err
+		})
+	},
+}
+
+func init() {
+	envCmd.Flags().StringVar(&envSettings.shell, "shell", defaultShell(), "shell to print the commands for: bash, zsh, fish, powershell or sh")
+	envCmd.Flags().BoolVar(&envSettings.unset, "unset", false, "print the commands undoing the environment")
+	rootCmd.AddCommand(envCmd)
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/x0f5c3/zerolog/log"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+// envNameEnv overrides the active env from the config, for a single shell
+const envNameEnv = "GOM_ENV"
+
+var envCreateSettings struct {
+	goVersion string
+}
+
+var envRemoveForce bool
+
+// envRegistry returns the registry of the named environments in the envs dir
+func envRegistry() *pkg.GOROOT {
+	return pkg.NewGOROOT(envsDir())
+}
+
+// activeEnvName returns the name of the active env, from GOM_ENV or else the config
+func activeEnvName() string {
+	if name := os.Getenv(envNameEnv); name != "" {
+		return name
+	}
+	if conf := gomconfig.Get(); conf != nil {
+		return conf.ActiveEnv
+	}
+	return ""
+}
+
+// activeEnv returns the active env, or nil if none is active or it's gone
+func activeEnv() *pkg.GOPATH {
+	name := activeEnvName()
+	if name == "" {
+		return nil
+	}
+	env, err := envRegistry().GetEnv(name)
+	if err != nil {
+		log.Warn().Err(err).Str("Env", name).Msg("the active env can't be used")
+		return nil
+	}
+	return env
+}
+
+type envInfo struct {
+	*pkg.GOPATH
+	Active bool `json:"active"`
+}
+
+var envCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a named GOPATH environment",
+	Long: "Create a named environment with its own GOPATH and GOBIN, optionally bound to a Go version.\n" +
+		"The version is resolved when the env is created, while it's active the shims, exec and current use it " +
+		"unless the project pins another one.",
+	Example: "go-manager env create work\ngo-manager env create legacy --go 1.20",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var version string
+		if envCreateSettings.goVersion != "" {
+			res := &currentResult{Query: envCreateSettings.goVersion, Source: sourceArgs}
+			err := res.resolveInstalled()
+			if errors.Is(err, pkg.ErrNoMatch) {
+				versions, err := getVersions(cmd.Context())
+				if err != nil {
+					return err
+				}
+				v, err := versions.Resolve(res.Query)
+				if err != nil {
+					return errors.Wrapf(err, "no go version matches %s", res.Query)
+				}
+				res.Version = v.Version
+			} else if err != nil {
+				return err
+			}
+			version = res.Version
+		}
+		env, err := envRegistry().CreateEnv(args[0], version)
+		if err != nil {
+			return err
+		}
+		return render("env_create", envInfo{GOPATH: env}, func() error {
+			pterm.Success.Printfln("Created %s in %s", env.EnvName, env.Dir)
+			if env.Go != "" && !isInstalled(env.Go) {
+				pterm.Warning.Printfln("%s isn't installed, install it with: go-manager install %s", env.Go, env.Go)
+			}
+			pterm.Info.Printfln("Activate it with: go-manager env activate %s", env.EnvName)
+			return nil
+		})
+	},
+}
+
+// isInstalled reports whether the toolchain of version is installed
+func isInstalled(version string) bool {
+	_, err := pkg.ReadToolchainVersion(pkg.InstallDir(envsDir(), version))
+	return err == nil
+}
+
+var envListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the named GOPATH environments",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envs, err := envRegistry().Envs()
+		if err != nil {
+			return err
+		}
+		active := activeEnvName()
+		infos := make([]envInfo, 0, len(envs))
+		for _, env := range envs {
+			infos = append(infos, envInfo{GOPATH: env, Active: env.EnvName == active})
+		}
+		return render("env_list", infos, func() error {
+			if len(infos) == 0 {
+				pterm.Info.Println("No envs, create one with: go-manager env create <name>")
+				return nil
+			}
+			data := pterm.TableData{{"Name", "Go", "GOPATH", "Active"}}
+			for _, e := range infos {
+				data = append(data, []string{e.EnvName, e.Go, e.Dir, mark(e.Active)})
+			}
+			return pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+		})
+	},
+}
+
+var envRemoveCmd = &cobra.Command{
+	Use:     "rm <name>",
+	Aliases: []string{"remove"},
+	Short:   "Remove a named GOPATH environment",
+	Long: "Remove a named environment with everything in its GOPATH. " +
+		"The active env, set with activate or " + envNameEnv + ", is only removed with --force.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conf := gomconfig.Get()
+		saved := conf != nil && conf.ActiveEnv == args[0]
+		// GOM_ENV may activate another env in this shell than the one saved in the config
+		active := saved || activeEnvName() == args[0]
+		if active && !envRemoveForce {
+			return errors.Errorf("%s is the active env, deactivate it first or use --force", args[0])
+		}
+		env, err := envRegistry().RemoveEnv(args[0])
+		if err != nil {
+			return err
+		}
+		if saved {
+			conf.SetActiveEnv("")
+			if err := conf.Save(); err != nil {
+				return errors.Wrap(err, "failed to save config")
+			}
+		}
+		return render("env_remove", envInfo{GOPATH: env, Active: active}, func() error {
+			pterm.Success.Printfln("Removed %s", env.EnvName)
+			return nil
+		})
+	},
+}
+
+// setActiveEnv saves name as the active env in the config, an empty name deactivates it
+func setActiveEnv(name string) error {
+	conf := gomconfig.Get()
+	if conf == nil {
+		return errors.New("no config loaded")
+	}
+	conf.SetActiveEnv(name)
+	return errors.Wrap(conf.Save(), "failed to save config")
+}
+
+var envActivateCmd = &cobra.Command{
+	Use:   "activate <name>",
+	Short: "Make a named GOPATH environment the active one",
+	Long: "Make a named environment the active one, its GOPATH and GOBIN are set up by env and shell-init from then on.\n" +
+		"Set " + envNameEnv + " to use another env in a single shell.",
+	Example: "go-manager env activate work\neval \"$(go-manager env)\"",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env, err := envRegistry().GetEnv(args[0])
+		if err != nil {
+			return err
+		}
+		if err := setActiveEnv(env.EnvName); err != nil {
+			return err
+		}
+		return render("env_activate", envInfo{GOPATH: env, Active: true}, func() error {
+			pterm.Success.Printfln("Activated %s", env.EnvName)
+			pterm.Info.Println("New shells set up by shell-init pick it up, update this one with: eval \"$(go-manager env)\"")
+			return nil
+		})
+	},
+}
+
+var envDeactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Go back to the default GOPATH",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setActiveEnv(""); err != nil {
+			return err
+		}
+		return render("env_deactivate", struct{}{}, func() error {
+			pterm.Success.Println("Deactivated the env, back to the default GOPATH")
+			return nil
+		})
+	},
+}
+
+func init() {
+	envCreateCmd.Flags().StringVar(&envCreateSettings.goVersion, "go", "", "go version to bind to the env")
+	envRemoveCmd.Flags().BoolVarP(&envRemoveForce, "force", "f", false, "remove the env even if it's the active one")
+	envCmd.AddCommand(envCreateCmd, envListCmd, envRemoveCmd, envActivateCmd, envDeactivateCmd)
+}
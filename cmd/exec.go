@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/pkg"
+	"github.com/x0f5c3/go-manager/pkg/progress"
+)
+
+var execInstall bool
+
+// toolchainEnv returns env set up for the toolchain in goroot: its bin in front of PATH, GOROOT and GOPATH,
+// and GOBIN on PATH too if an env is active
+func toolchainEnv(env []string, goroot string) []string {
+	env = pkg.ToolchainEnv(env, goroot)
+	path := filepath.Join(goroot, "bin")
+	if named := activeEnv(); named != nil {
+		path = named.BinDir + string(os.PathListSeparator) + path
+		env = pkg.SetEnv(env, "GOBIN", named.BinDir)
+	}
+	if p := pkg.GetEnv(env, "PATH"); p != "" {
+		path += string(os.PathListSeparator) + p
+	}
+	env = pkg.SetEnv(env, "PATH", path)
+	if p := gopath(); p != "" {
+		env = pkg.SetEnv(env, "GOPATH", p)
+	}
+	return env
+}
+
+// execArgs drops the -- between the version and the command, flag parsing stops at the version so it's kept
+func execArgs(args []string) []string {
+	if len(args) > 1 && args[1] == "--" {
+		return append([]string{args[0]}, args[2:]...)
+	}
+	return args
+}
+
+var execCmd = &cobra.Command{
+	Use:   "exec <version> -- <command> [args...]",
+	Short: "Run a command with a Go version without switching to it",
+	Long: "Run a command with the toolchain of a Go version on PATH and in GOROOT, the version in use stays as it is.\n" +
+		"The version is resolved against the installed toolchains, it's installed first with --install or auto_install set in the config. " +
+		"The exit code of the command is the one of go-manager.",
+	Example:     "go-manager exec 1.21 -- go test ./...\ngo-manager exec --install latest -- go version",
+	Annotations: map[string]string{stdoutAnnotation: ""},
+	Args: func(cmd *cobra.Command, args []string) error {
+		return cobra.MinimumNArgs(2)(cmd, execArgs(args))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		args = execArgs(args)
+		// stdout belongs to the command, installing reports on stderr
+		pterm.SetDefaultOutput(os.Stderr)
+		progress.SetDefault(progress.NewPTerm(os.Stderr))
+		res := &currentResult{Query: args[0], Source: sourceArgs}
+		err := res.resolveInstalled()
+		if errors.Is(err, pkg.ErrNoMatch) {
+			if conf := gomconfig.Get(); !execInstall && (conf == nil || !conf.AutoInstall) {
+				return errors.Wrapf(err, "no installed go version matches %s, install it with: go-manager install %s, "+
+					"or pass --install", res.Query, res.Query)
+			}
+			err = autoInstall(cmd.Context(), res)
+		}
+		if err != nil {
+			return err
+		}
+		// the command gets the interrupts from here on and decides when to stop
+		signal.Stop(interrupts)
+		code, err := pkg.RunCommand(args[1], args[2:], toolchainEnv(os.Environ(), res.Path))
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+		return nil
+	},
+}
+
+func init() {
+	execCmd.Flags().BoolVar(&execInstall, "install", false, "install the version if it isn't installed")
+	// flags after the version belong to the command
+	execCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(execCmd)
+}
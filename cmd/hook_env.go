@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/x0f5c3/zerolog/log"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+var hookEnvSettings struct {
+	shell string
+	stamp string
+}
+
+// hookEnv returns the lines moving the environment of the shell to the toolchain pinned in dir.
+// The toolchain is only resolved again when the pin file or its modification time changed since the last call,
+// which is tracked in the hook variables of the environment. Nothing is returned when nothing changed.
+func hookEnv(sh pkg.Shell, dir string) (string, error) {
+	var b strings.Builder
+	line := func(l string) { b.WriteString(l + "\n") }
+	if dir != os.Getenv(pkg.HookDirEnv) {
+		line(sh.Export(pkg.HookDirEnv, dir))
+	}
+	pin, err := pkg.FindPin(dir)
+	if err != nil {
+		return "", err
+	}
+	var pinFile string
+	var modTime time.Time
+	if pin != nil {
+		fi, err := os.Stat(pin.File)
+		if err != nil {
+			return "", err
+		}
+		pinFile, modTime = pin.File, fi.ModTime()
+	}
+	unchanged := pinFile == os.Getenv(pkg.HookPinEnv) &&
+		(pinFile == "" || strconv.FormatInt(modTime.UnixNano(), 10) == os.Getenv(pkg.HookModTimeEnv))
+	// a pinned toolchain that wasn't installed yet is looked for again on every call
+	if unchanged && (pinFile == "" || os.Getenv(pkg.HookBinEnv) != "") {
+		return b.String(), nil
+	}
+	var goroot string
+	// a project needing only a minimum version keeps the global toolchain when it's new enough
+	var satisfied bool
+	if pin != nil {
+		if fallback := fallbackQuery(); fallback != nil && fallback.satisfies(pin) {
+			satisfied = true
+		} else {
+			res := &currentResult{Query: pin.Query, Source: sourceProject, File: pin.File, Directive: pin.Directive}
+			if err := res.resolveInstalled(); err == nil {
+				goroot = res.Path
+			} else if !unchanged {
+				pterm.Warning.Printfln("%s set by %s isn't installed, install it with: go-manager install %s", res.Query, res.origin(), res.Query)
+			}
+		}
+	}
+	if prev := os.Getenv(pkg.HookBinEnv); prev != "" && prev != filepath.Join(goroot, "bin") {
+		line(sh.RemovePath(prev))
+	}
+	if goroot == "" {
+		line(sh.Unset(pkg.HookBinEnv))
+		// back to the global toolchain set up by shell-init
+		for _, v := range shellEnv().Vars {
+			if v.Name == "GOROOT" {
+				goroot = v.Value
+			}
+		}
+		if goroot == "" {
+			line(sh.Unset("GOROOT"))
+		} else {
+			line(sh.Export("GOROOT", goroot))
+		}
+	} else {
+		line(sh.PrependPath(filepath.Join(goroot, "bin")))
+		line(sh.Export(pkg.HookBinEnv, filepath.Join(goroot, "bin")))
+		line(sh.Export("GOROOT", goroot))
+	}
+	if pinFile == "" {
+		line(sh.Unset(pkg.HookPinEnv))
+		line(sh.Unset(pkg.HookModTimeEnv))
+		return b.String(), nil
+	}
+	line(sh.Export(pkg.HookPinEnv, pinFile))
+	line(sh.Export(pkg.HookModTimeEnv, strconv.FormatInt(modTime.UnixNano(), 10)))
+	// without a stamp the hook keeps calling until the toolchain is installed
+	if hookEnvSettings.stamp != "" && (goroot != "" || satisfied) {
+		touchStamp(hookEnvSettings.stamp, modTime)
+	}
+	return b.String(), nil
+}
+
+// touchStamp leaves the stamp the shell hook compares the pin file against, with the pin's modification time
+func touchStamp(path string, modTime time.Time) {
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		log.Debug().Err(err).Str("Path", path).Msg("failed to write the hook stamp")
+		return
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		log.Debug().Err(err).Str("Path", path).Msg("failed to set the time of the hook stamp")
+	}
+}
+
+var hookEnvCmd = &cobra.Command{
+	Use:   "hook-env",
+	Short: "Print the shell commands switching to the toolchain pinned in the working directory",
+	Long: "Print the shell commands switching to the toolchain pinned in the working directory, see current.\n" +
+		"It's run by the prompt hook shell-init sets up for bash, zsh and fish and only prints what changed since its last run.",
+	Hidden:      true,
+	Annotations: map[string]string{stdoutAnnotation: ""},
+	Args:        cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// it runs before every prompt, the config is only read
+		gomconfig.SetReadOnly()
+		// stdout is evaluated by the shell
+		pterm.SetDefaultOutput(os.Stderr)
+		sh, err := pkg.GetShell(hookEnvSettings.shell)
+		if err != nil {
+			return err
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		script, err := hookEnv(sh, wd)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(os.Stdout, script)
+		return err
+	},
+}
+
+func init() {
+	hookEnvCmd.Flags().StringVar(&hookEnvSettings.shell, "shell", defaultShell(), "shell to print the commands for: bash, zsh or fish")
+	hookEnvCmd.Flags().StringVar(&hookEnvSettings.stamp, "stamp", "", "file to leave with the modification time of the pin file")
+	rootCmd.AddCommand(hookEnvCmd)
+}
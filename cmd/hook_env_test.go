@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+func TestHookEnvUnchanged(t *testing.T) {
+	root := t.TempDir()
+	pinned := filepath.Join(root, "pinned")
+	if err := os.MkdirAll(pinned, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pin := filepath.Join(pinned, ".go-version")
+	if err := os.WriteFile(pin, []byte("1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := strconv.FormatInt(fi.ModTime().UnixNano(), 10)
+	sh := pkg.Shells["bash"]
+	tests := []struct {
+		name string
+		dir  string
+		env  map[string]string
+		want string
+	}{
+		{"no pin", root, map[string]string{pkg.HookDirEnv: root}, ""},
+		{"same pin", pinned, map[string]string{pkg.HookDirEnv: pinned, pkg.HookPinEnv: pin, pkg.HookModTimeEnv: modTime, pkg.HookBinEnv: "/go/bin"}, ""},
+		{"same pin in a subdirectory", filepath.Join(pinned, "sub"),
+			map[string]string{pkg.HookDirEnv: pinned, pkg.HookPinEnv: pin, pkg.HookModTimeEnv: modTime, pkg.HookBinEnv: "/go/bin"},
+			sh.Export(pkg.HookDirEnv, filepath.Join(pinned, "sub")) + "\n"},
+		{"new directory without a pin", root, nil, sh.Export(pkg.HookDirEnv, root) + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{pkg.HookDirEnv, pkg.HookPinEnv, pkg.HookModTimeEnv, pkg.HookBinEnv} {
+				t.Setenv(name, tt.env[name])
+			}
+			if err := os.MkdirAll(tt.dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			got, err := hookEnv(sh, tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("hookEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
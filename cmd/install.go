@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+var installSettings = pkg.NewDownloadSettings(fsutil.DefaultDataDir)
+
+type installResult struct {
+	Query            string `json:"query"`
+	Version          string `json:"version"`
+	Path             string `json:"path"`
+	AlreadyInstalled bool   `json:"already_installed"`
+}
+
+var installCmd = &cobra.Command{
+	Use:   "install <version>",
+	Short: "Download and install a Go toolchain",
+	Long: `Download and install the newest Go toolchain matching the given version query.
+
+The query can be one of:
+  latest          the newest release
+  latest-1        the newest release of the previous minor line
+  1.22            the newest release of the 1.22 line
+  1.21.5          exactly go1.21.5
+  ">=1.20 <1.22"  the newest release matching all constraints`,
+	Example: "go-manager install 1.22\ngo-manager install latest-1\ngo-manager install '>=1.20 <1.22'",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		versions, err := getVersions(cmd.Context())
+		if err != nil {
+			return err
+		}
+		version, err := versions.Resolve(args[0])
+		if err != nil {
+			return err
+		}
+		pterm.Info.Printfln("Resolved %s to %s", args[0], version.Version)
+		settings, err := downloadSettings(installSettings)
+		if err != nil {
+			return err
+		}
+		var dir string
+		err = withToolchainLock(version.Version, func() error {
+			dir, err = version.InstallContext(cmd.Context(), envsDir(), settings)
+			return err
+		})
+		res := installResult{Query: args[0], Version: version.Version, Path: dir}
+		if errors.Is(err, pkg.ErrInstalled) {
+			res.AlreadyInstalled = true
+		} else if err != nil {
+			return err
+		}
+		return render("install", res, func() error {
+			if res.AlreadyInstalled {
+				pterm.Warning.Printfln("%s is already installed in %s", version.Version, dir)
+			} else {
+				pterm.Success.Printfln("Installed %s to %s", version.Version, dir)
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	installSettings.AddToFlags(installCmd, false)
+	installCmd.Flags().BoolVar(&installSettings.Stream, "stream", false, "extract the archive while it's downloaded instead of saving it first")
+	rootCmd.AddCommand(installCmd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+type listEntry struct {
+	Version     string `json:"version"`
+	Stable      bool   `json:"stable"`
+	Installed   bool   `json:"installed"`
+	Active      bool   `json:"active"`
+	LatestMinor bool   `json:"latest_minor"`
+	Path        string `json:"path,omitempty"`
+}
+
+var listSettings = struct {
+	remote bool
+	json   bool
+}{}
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List installed and available Go toolchains",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := listEntries(cmd.Context(), listSettings.remote)
+		if err != nil {
+			return err
+		}
+		if listSettings.json {
+			useOutput(outputJSON)
+		}
+		return render("version_list", entries, func() error {
+			data := pterm.TableData{{"Version", "Installed", "Active", "Latest"}}
+			for _, e := range entries {
+				data = append(data, []string{e.Version, mark(e.Installed), mark(e.Active), mark(e.LatestMinor)})
+			}
+			return pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+		})
+	},
+}
+
+func mark(b bool) string {
+	if b {
+		return "*"
+	}
+	return ""
+}
+
+func listEntries(ctx context.Context, remote bool) ([]listEntry, error) {
+	dir := envsDir()
+	installed, err := pkg.InstalledVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	isInstalled := make(map[string]bool, len(installed))
+	for _, v := range installed {
+		isInstalled[v.Version] = true
+	}
+	versions := installed
+	if remote {
+		available, err := getVersions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range available {
+			if !isInstalled[v.Version] {
+				versions = append(versions, v)
+			}
+		}
+	}
+	sort.Sort(&versions)
+	latest := versions.LatestPerMinor()
+	entries := make([]listEntry, 0, len(versions))
+	for _, v := range versions {
+		e := listEntry{
+			Version:     v.Version,
+			Stable:      v.Stable,
+			Installed:   isInstalled[v.Version],
+			LatestMinor: latest[v.Version],
+		}
+		if e.Installed {
+			e.Path = pkg.InstallDir(dir, v.Version)
+			e.Active = isActive(v.Version)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func init() {
+	listCmd.Flags().BoolVarP(&listSettings.remote, "remote", "r", false, "include versions available for download")
+	listCmd.Flags().BoolVar(&listSettings.json, "json", false, "shorthand for --output json")
+	rootCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/pterm/pterm"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg/semver"
+)
+
+// setupList installs fake toolchains for versions, makes current the active one and serves the remote versions
+func setupList(t *testing.T, versions []string, current string, remote string) {
+	t.Helper()
+	gomconfig.SetReadOnly()
+	conf := gomconfig.Get()
+	saved := *conf
+	dataDir := fsutil.DefaultDataDir
+	t.Cleanup(func() {
+		*conf = saved
+		fsutil.DefaultDataDir = dataDir
+		listSettings.remote, listSettings.json = false, false
+		useOutput(outputTable)
+		pterm.SetDefaultOutput(os.Stdout)
+	})
+	fsutil.DefaultDataDir = t.TempDir()
+	conf.EnvsDir = filepath.Join(fsutil.DefaultDataDir, "envs")
+	for _, v := range versions {
+		dir := filepath.Join(conf.EnvsDir, v)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte(v+"\ntime 2023-12-05T00:00:00Z\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf.Current = nil
+	if current != "" {
+		release, err := semver.ParseRelease(current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.Current = release
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(remote))
+	}))
+	t.Cleanup(srv.Close)
+	conf.FeedURL = srv.URL
+	listCmd.SetContext(context.Background())
+}
+
+// captureStdout returns what fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- b
+	}()
+	err = fn()
+	os.Stdout = stdout
+	if closeErr := w.Close(); closeErr != nil {
+		t.Fatal(closeErr)
+	}
+	out := <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+const listRemote = `[{"version": "go1.22.0", "stable": true}, {"version": "go1.21.5", "stable": true},
+	{"version": "go1.21.4", "stable": true}, {"version": "go1.20.12", "stable": true}]`
+
+func TestListJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote bool
+		want   []listEntry
+	}{
+		{
+			name: "installed",
+			want: []listEntry{
+				{Version: "go1.21.5", Stable: true, Installed: true, LatestMinor: true},
+				{Version: "go1.21.4", Stable: true, Installed: true, Active: true},
+				{Version: "go1.20.12", Stable: true, Installed: true, LatestMinor: true},
+			},
+		},
+		{
+			name:   "remote",
+			remote: true,
+			want: []listEntry{
+				{Version: "go1.22.0", Stable: true, LatestMinor: true},
+				{Version: "go1.21.5", Stable: true, Installed: true, LatestMinor: true},
+				{Version: "go1.21.4", Stable: true, Installed: true, Active: true},
+				{Version: "go1.20.12", Stable: true, Installed: true, LatestMinor: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupList(t, []string{"go1.20.12", "go1.21.4", "go1.21.5"}, "go1.21.4", listRemote)
+			listSettings.remote, listSettings.json = tt.remote, true
+			out := captureStdout(t, func() error { return listCmd.RunE(listCmd, nil) })
+			var doc struct {
+				SchemaVersion int         `json:"schema_version"`
+				Kind          string      `json:"kind"`
+				Data          []listEntry `json:"data"`
+			}
+			if err := json.Unmarshal([]byte(out), &doc); err != nil {
+				t.Fatalf("invalid output %q: %v", out, err)
+			}
+			if doc.SchemaVersion != outputSchemaVersion || doc.Kind != "version_list" {
+				t.Errorf("document is schema %d of kind %q", doc.SchemaVersion, doc.Kind)
+			}
+			envs := gomconfig.Get().EnvsDir
+			for i := range tt.want {
+				if tt.want[i].Installed {
+					tt.want[i].Path = filepath.Join(envs, tt.want[i].Version)
+				}
+			}
+			if !reflect.DeepEqual(doc.Data, tt.want) {
+				t.Errorf("entries =\n%+v\nwant\n%+v", doc.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestListTable(t *testing.T) {
+	setupList(t, []string{"go1.20.12", "go1.21.4", "go1.21.5"}, "go1.21.4", listRemote)
+	listSettings.remote = true
+	var buf bytes.Buffer
+	pterm.SetDefaultOutput(&buf)
+	pterm.DisableStyling()
+	t.Cleanup(pterm.EnableStyling)
+	if err := listCmd.RunE(listCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var row []string
+		for _, cell := range strings.Split(line, "|") {
+			row = append(row, strings.TrimSpace(cell))
+		}
+		got = append(got, row)
+	}
+	want := [][]string{
+		{"Version", "Installed", "Active", "Latest"},
+		{"go1.22.0", "", "", "*"},
+		{"go1.21.5", "*", "", "*"},
+		{"go1.21.4", "*", "*", ""},
+		{"go1.20.12", "*", "", "*"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("table =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+// Report formats accepted by --report
+const (
+	reportJUnit = "junit"
+	reportJSON  = "json"
+)
+
+var matrixSettings = struct {
+	versions   []string
+	jobs       int
+	report     string
+	reportFile string
+	install    bool
+}{jobs: 1, report: reportJUnit}
+
+type matrixResult struct {
+	LogDir  string             `json:"log_dir"`
+	Report  string             `json:"report"`
+	Passed  bool               `json:"passed"`
+	Results []pkg.MatrixResult `json:"results"`
+}
+
+// matrixJobs resolves the version queries to toolchains, installing missing ones with --install or auto_install.
+// Queries resolving to the same version run once.
+func matrixJobs(ctx context.Context, queries []string) ([]pkg.MatrixJob, error) {
+	install := matrixSettings.install
+	if conf := gomconfig.Get(); conf != nil && conf.AutoInstall {
+		install = true
+	}
+	var jobs []pkg.MatrixJob
+	seen := make(map[string]bool)
+	for _, q := range queries {
+		q = strings.TrimSpace(q)
+		if q == "" {
+			continue
+		}
+		res := &currentResult{Query: q, Source: sourceArgs}
+		err := res.resolveInstalled()
+		if errors.Is(err, pkg.ErrNoMatch) {
+			if !install {
+				return nil, errors.Wrapf(err, "no installed go version matches %s, install it with: go-manager install %s, "+
+					"or pass --install", q, q)
+			}
+			err = autoInstall(ctx, res)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[res.Version] {
+			continue
+		}
+		seen[res.Version] = true
+		jobs = append(jobs, pkg.MatrixJob{Query: q, Version: res.Version, Env: toolchainEnv(os.Environ(), res.Path)})
+	}
+	if len(jobs) == 0 {
+		return nil, errors.New("no versions to run, pass them with --versions")
+	}
+	return jobs, nil
+}
+
+// writeReport writes the report of the results to path in the format set with --report
+func writeReport(path string, results []pkg.MatrixResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the report")
+	}
+	defer f.Close()
+	if matrixSettings.report == reportJSON {
+		return pkg.WriteMatrixJSON(f, results)
+	}
+	return pkg.WriteMatrixJUnit(f, "go-manager matrix", results)
+}
+
+var matrixCmd = &cobra.Command{
+	Use:   "matrix --versions <versions> -- <command> [args...]",
+	Short: "Run a command once per Go version",
+	Long: "Run a command once per Go version and report which versions pass.\n" +
+		"The versions are resolved against the installed toolchains like for exec, the output of every run is kept " +
+		"in a directory under the log dir next to a JUnit XML or JSON report. " +
+		"go-manager exits with 1 when the command fails for any version.",
+	Example: "go-manager matrix --versions 1.20,1.21,latest -- go test ./...\n" +
+		"go-manager matrix --versions 1.21,1.22 -j 2 --report json --report-file report.json -- go vet ./...",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch matrixSettings.report {
+		case reportJUnit, reportJSON:
+		default:
+			return errors.Errorf("unknown report format %q, use %s or %s", matrixSettings.report, reportJUnit, reportJSON)
+		}
+		jobs, err := matrixJobs(cmd.Context(), matrixSettings.versions)
+		if err != nil {
+			return err
+		}
+		logDir := filepath.Join(fsutil.TodayLogDirPath(fsutil.DefaultLogDir), "matrix-"+time.Now().Format("15_04_05"))
+		results, runErr := pkg.RunMatrix(cmd.Context(), jobs, args[0], args[1:], logDir, matrixSettings.jobs, func(r pkg.MatrixResult) {
+			switch r.Status {
+			case pkg.MatrixPass:
+				pterm.Success.Printfln("%s passed in %s", r.Version, r.Duration.Round(time.Millisecond))
+			case pkg.MatrixFail:
+				pterm.Error.Printfln("%s failed with exit code %d in %s", r.Version, r.ExitCode, r.Duration.Round(time.Millisecond))
+			case pkg.MatrixCancelled:
+				pterm.Warning.Printfln("%s cancelled", r.Version)
+			default:
+				pterm.Error.Printfln("%s: %s", r.Version, r.Error)
+			}
+		})
+		if results == nil {
+			return runErr
+		}
+		report := matrixSettings.reportFile
+		if report == "" {
+			report = filepath.Join(logDir, "report.xml")
+			if matrixSettings.report == reportJSON {
+				report = filepath.Join(logDir, "report.json")
+			}
+		}
+		if err := writeReport(report, results); err != nil {
+			return err
+		}
+		// an interrupted matrix still reports the versions that finished, the others are cancelled
+		if runErr != nil {
+			return errors.Wrapf(runErr, "matrix interrupted, partial report written to %s", report)
+		}
+		res := matrixResult{LogDir: logDir, Report: report, Passed: pkg.Passed(results), Results: results}
+		err = render("matrix", res, func() error {
+			data := pterm.TableData{{"Version", "Query", "Status", "Exit code", "Time", "Output"}}
+			failed := 0
+			for _, r := range results {
+				if r.Status != pkg.MatrixPass {
+					failed++
+				}
+				data = append(data, []string{r.Version, r.Query, r.Status, strconv.Itoa(r.ExitCode),
+					r.Duration.Round(time.Millisecond).String(), r.StdoutLog})
+			}
+			if err := pterm.DefaultTable.WithHasHeader().WithData(data).Render(); err != nil {
+				return err
+			}
+			pterm.Info.Printfln("Report written to %s", report)
+			if failed > 0 {
+				pterm.Error.Printfln("%d of %d versions failed", failed, len(results))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !res.Passed {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+func init() {
+	matrixCmd.Flags().StringSliceVar(&matrixSettings.versions, "versions", nil, "comma separated version queries to run the command with")
+	matrixCmd.Flags().IntVarP(&matrixSettings.jobs, "jobs", "j", matrixSettings.jobs, "how many versions to run at the same time")
+	matrixCmd.Flags().StringVar(&matrixSettings.report, "report", matrixSettings.report, "report format: junit or json")
+	matrixCmd.Flags().StringVar(&matrixSettings.reportFile, "report-file", "", "where to write the report, defaults to the log directory of the run")
+	matrixCmd.Flags().BoolVar(&matrixSettings.install, "install", false, "install the versions that aren't installed")
+	_ = matrixCmd.MarkFlagRequired("versions")
+	rootCmd.AddCommand(matrixCmd)
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+// outputSchemaVersion is bumped whenever a structured document changes in a way that breaks consumers
+const outputSchemaVersion = 1
+
+// Output formats accepted by --output
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputFormat is set by the global --output flag
+var outputFormat = outputTable
+
+// document is the envelope of everything printed with --output json or yaml
+type document struct {
+	SchemaVersion int            `json:"schema_version"`
+	Kind          string         `json:"kind"`
+	Data          interface{}    `json:"data,omitempty"`
+	Error         *errorDocument `json:"error,omitempty"`
+}
+
+type errorDocument struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// exitCodes maps errors to the code and exit status reported for them, the first match wins
+var exitCodes = []struct {
+	err  error
+	code string
+	exit int
+}{
+	{pkg.ErrNoMatch, "no_match", 3},
+	{pkg.ErrNotInstalled, "not_installed", 4},
+	{pkg.ErrChecksumMismatch, "checksum_mismatch", 5},
+	{pkg.ErrNoCache, "no_cache", 6},
+	{errNoCurrent, "no_current", 7},
+}
+
+// describeError returns the code and exit status for err
+func describeError(err error) (string, int) {
+	if pkg.IsCancelled(err) || errors.Is(err, context.Canceled) {
+		return "cancelled", interruptExitCode
+	}
+	for _, c := range exitCodes {
+		if errors.Is(err, c.err) {
+			return c.code, c.exit
+		}
+	}
+	return "error", 1
+}
+
+// structuredOutput reports whether commands print documents instead of tables and messages
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// initOutput validates --output and keeps everything but the documents off stdout when it's structured
+func initOutput() {
+	switch outputFormat {
+	case outputJSON, outputYAML:
+		pterm.SetDefaultOutput(os.Stderr)
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+	case outputTable:
+	default:
+		pterm.Warning.Printfln("unknown output format %q, using %s", outputFormat, outputTable)
+		outputFormat = outputTable
+	}
+}
+
+// useOutput switches the output format after flags were parsed, for flags like --json
+func useOutput(format string) {
+	outputFormat = format
+	initOutput()
+}
+
+// render prints data as a document of the given kind, or calls table when the output isn't structured
+func render(kind string, data interface{}, table func() error) error {
+	if !structuredOutput() {
+		return table()
+	}
+	return writeDocument(document{SchemaVersion: outputSchemaVersion, Kind: kind, Data: data})
+}
+
+// renderError prints err as an error document and returns the exit status for it
+func renderError(err error) int {
+	code, exit := describeError(err)
+	if structuredOutput() {
+		doc := document{
+			SchemaVersion: outputSchemaVersion,
+			Kind:          "error",
+			Error:         &errorDocument{Code: code, Message: err.Error(), ExitCode: exit},
+		}
+		if err := writeDocument(doc); err != nil {
+			pterm.Error.Println(err)
+		}
+	}
+	return exit
+}
+
+func writeDocument(doc document) error {
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if outputFormat == outputJSON {
+		_, err = os.Stdout.Write(append(b, '\n'))
+		return err
+	}
+	// going through json keeps the field names and order of both formats the same
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle drops the flow style and quoting yaml picks up from json, except for empty collections
+func blockStyle(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode || len(node.Content) > 0 {
+		node.Style = 0
+	}
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
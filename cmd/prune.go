@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+var pruneSettings = struct {
+	keep   int
+	dryRun bool
+	yes    bool
+}{}
+
+// pruneEntry is a toolchain or leftover download prune found, Removed is false for a dry run or when it was kept
+type pruneEntry struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Removed bool   `json:"removed"`
+}
+
+type pruneResult struct {
+	DryRun  bool         `json:"dry_run"`
+	Entries []pruneEntry `json:"entries"`
+	Freed   int64        `json:"freed"`
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old Go toolchains and leftover downloads",
+	Long: `Remove all but the newest --keep patch releases of every installed minor line,
+along with archives left in the data directory by interrupted downloads.
+The active toolchain, the one in use in the working directory, like a version pinned by the project,
+and versions pinned in the config are always kept. Downloads of a running install are left to it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pruneSettings.keep < 1 {
+			return errors.New("--keep has to be at least 1")
+		}
+		installed, err := pkg.InstalledVersions(envsDir())
+		if err != nil {
+			return err
+		}
+		var pinned []string
+		if conf := gomconfig.Get(); conf != nil {
+			pinned = append(pinned, conf.Pinned...)
+		}
+		for _, v := range installed {
+			if isActive(v.Version) {
+				pinned = append(pinned, v.Version)
+			}
+		}
+		// the version in use in the working directory, like one pinned by the project
+		if wd, err := os.Getwd(); err == nil {
+			if res, err := selectQuery(wd); err == nil && res.resolveInstalled() == nil {
+				pinned = append(pinned, res.Version)
+			}
+		}
+		var toRemove []string
+		for _, v := range installed.PruneCandidates(pruneSettings.keep, pinned...) {
+			toRemove = append(toRemove, pkg.InstallDir(envsDir(), v.Version))
+		}
+		archives, err := pkg.OrphanedArchives(fsutil.DefaultDataDir)
+		if err != nil {
+			return err
+		}
+		toRemove = append(toRemove, archives...)
+		res := pruneResult{DryRun: pruneSettings.dryRun, Entries: []pruneEntry{}}
+		table := func() error {
+			if res.Freed > 0 {
+				pterm.Success.Printfln("Freed %s", formatSize(res.Freed))
+			}
+			return nil
+		}
+		if len(toRemove) == 0 {
+			pterm.Info.Println("Nothing to prune")
+			return render("prune", res, table)
+		}
+		var total int64
+		data := pterm.TableData{{"Path", "Size"}}
+		for _, path := range toRemove {
+			size, err := pkg.DirSize(path)
+			if err != nil {
+				pterm.Debug.Printfln("failed to get size of %s: %s", path, err)
+			}
+			total += size
+			res.Entries = append(res.Entries, pruneEntry{Path: path, Size: size})
+			data = append(data, []string{path, formatSize(size)})
+		}
+		// with structured output the entries and the prompt go to stderr
+		if err := pterm.DefaultTable.WithHasHeader().WithData(data).Render(); err != nil {
+			return err
+		}
+		pterm.Info.Printfln("%d entries, %s total", len(toRemove), formatSize(total))
+		if pruneSettings.dryRun {
+			return render("prune", res, table)
+		}
+		if !pruneSettings.yes {
+			ok, err := pterm.DefaultInteractiveConfirm.WithDefaultText("Remove them?").Show()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return render("prune", res, table)
+			}
+		}
+		for i := range res.Entries {
+			entry := &res.Entries[i]
+			path := entry.Path
+			remove := func() error {
+				// an install may have finished with the file while the lock was waited for
+				if _, err := os.Lstat(path); os.IsNotExist(err) {
+					return nil
+				}
+				// or use switched to the toolchain
+				if pkg.CurrentTarget(fsutil.DefaultDataDir) == path {
+					return nil
+				}
+				if err := os.RemoveAll(path); err != nil {
+					return errors.Wrapf(err, "failed to remove %s", path)
+				}
+				entry.Removed = true
+				return nil
+			}
+			// toolchains and the downloads of a running install are locked by it
+			version := pkg.ArchiveVersion(path)
+			if filepath.Dir(path) == envsDir() {
+				version = filepath.Base(path)
+			}
+			if version != "" {
+				err = withToolchainLock(version, remove)
+			} else {
+				err = remove()
+			}
+			if err != nil {
+				return err
+			}
+			if entry.Removed {
+				res.Freed += entry.Size
+				pterm.Success.Printfln("Removed %s", filepath.Base(path))
+			}
+		}
+		return render("prune", res, table)
+	},
+}
+
+func init() {
+	pruneCmd.Flags().IntVarP(&pruneSettings.keep, "keep", "n", 1, "number of patch releases to keep per minor line")
+	pruneCmd.Flags().BoolVar(&pruneSettings.dryRun, "dry-run", false, "only print what would be removed")
+	pruneCmd.Flags().BoolVarP(&pruneSettings.yes, "yes", "y", false, "don't ask for confirmation")
+	rootCmd.AddCommand(pruneCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+var resolveInstalled bool
+
+type resolution struct {
+	Query     string `json:"query"`
+	Version   string `json:"version"`
+	Stable    bool   `json:"stable"`
+	Installed bool   `json:"installed"`
+	Path      string `json:"path,omitempty"`
+}
+
+var resolveCmd = &cobra.Command{
+	Use:     "resolve <version>",
+	Short:   "Print the Go version a version query resolves to",
+	Long:    "Print the newest Go version matching the given version query, see install for the query format.",
+	Example: "go-manager resolve latest-1\ngo-manager resolve --installed 1.21",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installed, err := pkg.InstalledVersions(envsDir())
+		if err != nil {
+			return err
+		}
+		versions := installed
+		if !resolveInstalled {
+			if versions, err = getVersions(cmd.Context()); err != nil {
+				return err
+			}
+		}
+		version, err := versions.Resolve(args[0])
+		if err != nil {
+			return err
+		}
+		res := resolution{Query: args[0], Version: version.Version, Stable: version.Stable}
+		for _, v := range installed {
+			if v.Version == version.Version {
+				res.Installed = true
+				res.Path = pkg.InstallDir(envsDir(), v.Version)
+			}
+		}
+		return render("resolution", res, func() error {
+			// only the version, so the table output can be used in scripts too
+			pterm.Fprintln(os.Stdout, version.Version)
+			return nil
+		})
+	},
+}
+
+func init() {
+	resolveCmd.Flags().BoolVarP(&resolveInstalled, "installed", "i", false, "only consider installed toolchains")
+	rootCmd.AddCommand(resolveCmd)
+}
@@ -1,15 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/pterm/pcli"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
 	"github.com/x0f5c3/go-manager/internal/fsutil"
 	"github.com/x0f5c3/go-manager/pkg"
+	"github.com/x0f5c3/go-manager/pkg/progress"
+	"github.com/x0f5c3/manic-go/pkg/downloader"
 )
 
 var rootCmd = &cobra.Command{
@@ -20,31 +25,197 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following lines if your bare application has an action associated with it:
 	RunE: func(cmd *cobra.Command, args []string) error {
 		dlSettings.OutDir = args[0]
-		return pkg.DownloadLatest(&dlSettings)
+		settings, err := downloadSettings(&dlSettings)
+		if err != nil {
+			return err
+		}
+		return pkg.DownloadLatestContext(cmd.Context(), settings)
 	},
 }
 
 var dlSettings = pkg.DownloadSettings{}
 
+const (
+	// interruptGrace is how long cancelled commands get to clean up before exiting on user interrupt
+	interruptGrace = 5 * time.Second
+	// interruptExitCode is the exit code after a user interrupt, like a shell reports for SIGINT
+	interruptExitCode = 130
+)
+
+// unstable includes beta and release candidate versions when listing and resolving versions
+var unstable bool
+
+// offline serves the version index only from the cache
+var offline bool
+
+// mirror overrides the mirror from the config and GOM_MIRROR for the version index and downloads
+var mirror string
+
+// mirrorURL returns the mirror set with --mirror, or else the one from the config or GOM_MIRROR
+func mirrorURL() string {
+	if mirror != "" {
+		return mirror
+	}
+	if conf := gomconfig.Get(); conf != nil {
+		return conf.Mirror
+	}
+	return ""
+}
+
+// lockTimeout is how long to wait for other go-manager processes working on the same toolchains
+var lockTimeout = fsutil.DefaultLockTimeout
+
+// initLockTimeout makes saving the config wait as long as set with --lock-timeout
+func initLockTimeout() {
+	gomconfig.LockTimeout = lockTimeout
+}
+
+// progressMode selects how progress is reported, see initProgress
+var progressMode string
+
+// initProgress sets the reporter for downloads, extraction and config loading from the --progress flag
+func initProgress() {
+	switch progressMode {
+	case "json":
+		progress.SetDefault(progress.NewJSON(os.Stderr))
+	case "none":
+		progress.SetDefault(progress.Nop)
+	default:
+		if progressMode != "pterm" {
+			pterm.Warning.Printfln("unknown progress mode %q, using pterm", progressMode)
+		}
+		if structuredOutput() {
+			// keep the bars off stdout, it's reserved for the documents
+			progress.SetDefault(progress.NewPTerm(os.Stderr))
+			return
+		}
+		progress.SetDefault(progress.PTerm)
+	}
+}
+
+// client is the HTTP client shared by the index and downloads, created on first use by httpClient
+var client *downloader.Client
+
+// httpClient returns the HTTP client honouring the proxies from the config
+func httpClient() (*downloader.Client, error) {
+	if client != nil {
+		return client, nil
+	}
+	var proxies []string
+	if conf := gomconfig.Get(); conf != nil {
+		proxies = conf.Proxies
+	}
+	c, err := pkg.NewClient(proxies)
+	if err != nil {
+		return nil, err
+	}
+	client = c
+	return client, nil
+}
+
+// getVersions fetches the available versions according to the global flags
+func getVersions(ctx context.Context) (pkg.Versions, error) {
+	c, err := httpClient()
+	if err != nil {
+		return nil, err
+	}
+	ttl := gomconfig.DefaultIndexTTL
+	var feedURL string
+	if conf := gomconfig.Get(); conf != nil {
+		ttl = conf.IndexCacheTTL()
+		feedURL = conf.FeedURL
+	}
+	return pkg.GetVersionsContext(ctx,
+		pkg.WithClient(c),
+		pkg.WithUnstable(unstable),
+		pkg.WithCache(fsutil.DefaultDataDir, ttl),
+		pkg.WithOffline(offline),
+		pkg.WithMirror(mirrorURL()),
+		pkg.WithFeedURL(feedURL),
+	)
+}
+
+// downloadSettings fills in the download settings taken from the config
+func downloadSettings(settings *pkg.DownloadSettings) (*pkg.DownloadSettings, error) {
+	if settings.Mirror == "" {
+		settings.Mirror = mirrorURL()
+	}
+	c, err := httpClient()
+	if err != nil {
+		return nil, err
+	}
+	settings.Client = c
+	settings.Cache = downloadCache()
+	return settings, nil
+}
+
+// interrupts receives the user interrupts cancelling the command, commands handing them to a child process stop it
+var interrupts = make(chan os.Signal, 1)
+
 // Execute adds all child commands to the root command and sets flags appropriately.
-// This is called by main.main(). It only needs to happen once to the rootCmd.
+// This is called by main.main() after ExecShim. It only needs to happen once to the rootCmd.
 func Execute() {
-	// Fetch user interrupt
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	// the prompt hook prints only what the shell evaluates and never checks for updates
+	if cmd, _, err := rootCmd.Find(os.Args[1:]); err != nil || cmd != hookEnvCmd {
+		setupPcli()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Fetch user interrupt, cancel running downloads and give them a moment to clean up.
+	// A second interrupt exits right away.
+	signal.Notify(interrupts, os.Interrupt)
 	go func() {
-		<-c
+		<-interrupts
 		pterm.Warning.Println("user interrupt")
-		checkUpdate()
-		os.Exit(0)
+		cancel()
+		select {
+		case <-interrupts:
+		case <-time.After(interruptGrace):
+			if cmd, _, err := rootCmd.Find(os.Args[1:]); err == nil && !keepsStdout(cmd) {
+				checkUpdate()
+			}
+		}
+		os.Exit(interruptExitCode)
 	}()
 
 	// Execute cobra
-	if err := rootCmd.Execute(); err != nil {
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if err != nil {
+		exit := renderError(err)
+		if !keepsStdout(cmd) {
+			checkUpdate()
+		}
+		os.Exit(exit)
+	}
+	if !keepsStdout(cmd) {
 		checkUpdate()
+	}
+}
+
+// stdoutAnnotation marks commands whose stdout belongs to something else, like scripts for shells to evaluate
+// or the output of a child process, nothing else may end up on it
+const stdoutAnnotation = "stdout"
+
+// keepsStdout reports whether nothing but the output of cmd may end up on stdout, like for env and the completions
+func keepsStdout(cmd *cobra.Command) bool {
+	if cmd == nil {
+		return false
+	}
+	if _, ok := cmd.Annotations[stdoutAnnotation]; ok {
+		return true
+	}
+	return cmd.HasParent() && cmd.Parent().Name() == "completion"
+}
+
+// setupPcli uses https://github.com/pterm/pcli to style the output of cobra and check for updates
+func setupPcli() {
+	err := pcli.SetRepo("x0f5c3/go-manager")
+	if err != nil {
+		pterm.Fatal.Println(err)
 		os.Exit(1)
 	}
-	checkUpdate()
+	pcli.SetRootCmd(rootCmd)
+	pcli.Setup()
 }
 
 func checkUpdate() {
@@ -61,22 +232,18 @@ func init() {
 	// Fill the empty strings with the shorthand variant (if you like to have one).
 	rootCmd.PersistentFlags().BoolVarP(&pterm.PrintDebugMessages, "debug", "d", false, "enable debug messages")
 	rootCmd.PersistentFlags().BoolVar(&pterm.RawOutput, "raw", false, "print unstyled raw output (set it if output is written to a file)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputTable, "output format: table, json or yaml")
 	rootCmd.PersistentFlags().BoolVar(&pcli.DisableUpdateChecking, "disable-update-checks", false, "disables update checks")
+	rootCmd.PersistentFlags().BoolVar(&unstable, "unstable", false, "include beta and release candidate versions")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "only use the cached version index")
+	rootCmd.PersistentFlags().StringVar(&mirror, "mirror", "", "mirror of "+pkg.DefaultMirror+" to fetch versions and archives from, overrides GOM_MIRROR")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", lockTimeout, "how long to wait for other go-manager processes, 0 waits forever")
+	rootCmd.PersistentFlags().StringVar(&progressMode, "progress", "pterm", "how to report progress: pterm, json lines on stderr or none")
 	rootCmd.Flags().StringVarP(&dlSettings.Arch, "arch", "a", pkg.CurrentKind.Arch, "architecture")
 	rootCmd.Flags().StringVarP(&dlSettings.Os, "os", "o", pkg.CurrentKind.Os, "operating system")
 	rootCmd.Flags().StringVarP(&dlSettings.Kind, "kind", "k", pkg.CurrentKind.Kind, "kind")
-	cobra.OnInitialize(mustInitConfig)
-
-	// Use https://github.com/pterm/pcli to style the output of cobra.
-	err := pcli.SetRepo("x0f5c3/go-manager")
-	if err != nil {
-		pterm.Fatal.Println(err)
-		os.Exit(1)
-	}
-	pcli.SetRootCmd(rootCmd)
-	pcli.Setup()
+	cobra.OnInitialize(initOutput, initProgress, initLockTimeout)
 
 	// Change global PTerm theme
 	pterm.ThemeDefault.SectionStyle = *pterm.NewStyle(pterm.FgCyan)
-	rootCmd.AddCommand(fsutil.InstallCmd)
 }
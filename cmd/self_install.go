@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/x0f5c3/zerolog/log"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+)
+
+var selfInstallCmd = &cobra.Command{
+	Use:    "self-install [directory]",
+	Short:  "Install gom to the given directory",
+	Long:   `Install gom to the given directory`,
+	Args:   cobra.MaximumNArgs(1),
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dataDir := fsutil.DefaultDataDir
+		if len(args) > 0 {
+			dataDir = args[0]
+		}
+		exists, perms := fsutil.CheckExistsWritable(dataDir)
+		if !exists {
+			log.Info().Str("Path", dataDir).Msg("data directory doesn't exist")
+		}
+		if !perms {
+			log.Error().Err(fmt.Errorf("no write permissions")).Str("Path", dataDir).Msg("data directory is not writable")
+			return fmt.Errorf("data directory is not writable")
+		}
+		installInfo, err := fsutil.CopyAppToDataDir(dataDir)
+		if err != nil {
+			return err
+		}
+		b, err := toml.Marshal(gomconfig.Conf)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to marshal config")
+			return errors.Wrap(err, "failed to marshal config")
+		}
+		if err := os.WriteFile(installInfo.ConfigPath, b, 0644); err != nil {
+			log.Error().Err(err).Msgf("failed to write %s", installInfo.ConfigPath)
+			return errors.Wrap(err, "failed to write config")
+		}
+		b, err = toml.Marshal(installInfo)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to marshal install info")
+			return errors.Wrap(err, "failed to marshal install info")
+		}
+		if err := os.WriteFile(filepath.Join(dataDir, "install.toml"), b, 0644); err != nil {
+			log.Error().Err(err).Msgf("failed to write %s", filepath.Join(dataDir, "install.toml"))
+			return errors.Wrap(err, "failed to write install info")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selfInstallCmd)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pterm/pcli"
+	"github.com/spf13/cobra"
+
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+// shellRCs tells how to load the init snippet in every shell
+var shellRCs = map[string]string{
+	"bash":       `add to ~/.bashrc: eval "$(go-manager shell-init bash)"`,
+	"zsh":        `add to ~/.zshrc after compinit: eval "$(go-manager shell-init zsh)"`,
+	"sh":         `add to ~/.profile: eval "$(go-manager shell-init sh)"`,
+	"fish":       `add to ~/.config/fish/config.fish: go-manager shell-init fish | source`,
+	"powershell": `add to $PROFILE: go-manager shell-init powershell | Out-String | Invoke-Expression`,
+}
+
+type shellInitResult struct {
+	Shell  string `json:"shell"`
+	Script string `json:"script"`
+}
+
+// shellInit returns the init snippet for sh
+func shellInit(sh pkg.Shell) (string, error) {
+	self, err := fsutil.FindMyself()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("# go-manager shell integration for " + sh.Name() + ", " + shellRCs[sh.Name()] + "\n")
+	b.WriteString(shellEnv().InitScript(sh, self))
+	return b.String(), nil
+}
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init <shell>",
+	Short: "Print the snippet integrating go-manager into a shell",
+	Long: "Print the snippet integrating go-manager into a shell: the shims and the active toolchain on PATH, GOROOT, GOPATH and completions.\n" +
+		"In bash, zsh and fish a prompt hook switches to the toolchain pinned by the project when the directory changes.\n" +
+		"Supported shells are bash, zsh, fish, powershell and sh.",
+	Example:     "eval \"$(go-manager shell-init bash)\"\ngo-manager shell-init fish | source",
+	Annotations: map[string]string{stdoutAnnotation: ""},
+	Args:        cobra.ExactArgs(1),
+	ValidArgs:   pkg.ShellNames(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sh, err := pkg.GetShell(args[0])
+		if err != nil {
+			return err
+		}
+		script, err := shellInit(sh)
+		if err != nil {
+			return err
+		}
+		return render("shell_init", shellInitResult{Shell: sh.Name(), Script: script}, func() error {
+			_, err := fmt.Fprint(os.Stdout, script)
+			return err
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+	// pcli swallows the output of the commands cobra adds itself, the completion commands have to be
+	// created while the root writes to stdout so the scripts reach it
+	rootCmd.SetOut(os.Stdout)
+	rootCmd.InitDefaultCompletionCmd()
+	rootCmd.SetOut(pcli.PcliOut())
+}
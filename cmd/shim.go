@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+	"github.com/x0f5c3/go-manager/pkg/progress"
+)
+
+// shimDir is where the shims are installed, it has to come before any other go on PATH
+var shimDir = pkg.AliasDir
+
+// runShim runs the toolchain binary name of the go version in use in the working directory with args.
+// It's what go-manager does when it's started through a shim, it only returns on failure.
+func runShim(name string, args []string) (int, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return 1, err
+	}
+	res, err := selectQuery(wd)
+	if err != nil {
+		return 1, err
+	}
+	err = res.resolveInstalled()
+	if errors.Is(err, pkg.ErrNoMatch) {
+		if conf := gomconfig.Get(); conf == nil || !conf.AutoInstall {
+			return 1, errors.Errorf("no installed go version matches %s set by %s, install it with: go-manager install %s, "+
+				"or set auto_install in the config", res.Query, res.origin(), res.Query)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err = autoInstall(ctx, res)
+		stop()
+		if err != nil {
+			return 1, err
+		}
+	} else if err != nil {
+		return 1, errors.Wrapf(err, "invalid version set by %s", res.origin())
+	}
+	return pkg.ExecToolchain(pkg.ToolchainBinary(res.Path, name), args, pkg.ToolchainEnv(os.Environ(), res.Path))
+}
+
+// autoInstall installs the newest toolchain matching the query of res and resolves res to it.
+// Other processes installing the same toolchain at the same time are waited for.
+func autoInstall(ctx context.Context, res *currentResult) error {
+	versions, err := getVersions(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := versions.Resolve(res.Query)
+	if err != nil {
+		return errors.Wrapf(err, "no go version matches %s set by %s", res.Query, res.origin())
+	}
+	settings, err := downloadSettings(pkg.NewDownloadSettings(fsutil.DefaultDataDir))
+	if err != nil {
+		return err
+	}
+	err = withToolchainLock(version.Version, func() error {
+		pterm.Info.Printfln("Installing %s set by %s", version.Version, res.origin())
+		_, err := version.InstallContext(ctx, envsDir(), settings)
+		return err
+	})
+	if err != nil && !errors.Is(err, pkg.ErrInstalled) {
+		return err
+	}
+	return res.resolveInstalled()
+}
+
+// ExecShim runs the toolchain and exits if go-manager was started through a go or gofmt shim.
+// main calls it before anything else, the config is only read, never created or saved, by shims.
+func ExecShim() {
+	name, ok := pkg.ShimName(os.Args[0])
+	if !ok {
+		return
+	}
+	gomconfig.SetReadOnly()
+	// stdout belongs to the toolchain, auto install reports on stderr
+	pterm.SetDefaultOutput(os.Stderr)
+	progress.SetDefault(progress.NewPTerm(os.Stderr))
+	code, err := runShim(name, os.Args[1:])
+	if err != nil {
+		pterm.Error.Println(err)
+	}
+	os.Exit(code)
+}
+
+type shimResult struct {
+	Dir   string   `json:"dir"`
+	Shims []string `json:"shims"`
+}
+
+var shimCmd = &cobra.Command{
+	Use:   "shim",
+	Short: "Manage the go and gofmt shims",
+	Long: "Manage the go and gofmt shims.\n" +
+		"The shims run the toolchain of the go version in use in the working directory, see current, " +
+		"so different projects and terminals can use different versions at the same time. " +
+		"Put the shim directory in front of PATH to use them.",
+}
+
+var shimInstallCmd = &cobra.Command{
+	Use:     "install",
+	Short:   "Install the go and gofmt shims",
+	Example: "go-manager shim install\ngo-manager shim install --dir ~/bin",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		self, err := fsutil.FindMyself()
+		if err != nil {
+			return err
+		}
+		shims, err := pkg.InstallShims(shimDir, self)
+		if err != nil {
+			return err
+		}
+		return render("shim_install", shimResult{Dir: shimDir, Shims: shims}, func() error {
+			pterm.Success.Printfln("Installed the shims to %s", shimDir)
+			pterm.Info.Printfln("Put %s in front of PATH to use them", shimDir)
+			return nil
+		})
+	},
+}
+
+var shimRemoveCmd = &cobra.Command{
+	Use:     "rm",
+	Aliases: []string{"uninstall"},
+	Short:   "Remove the go and gofmt shims",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shims, err := pkg.RemoveShims(shimDir)
+		if err != nil {
+			return err
+		}
+		return render("shim_remove", shimResult{Dir: shimDir, Shims: shims}, func() error {
+			if len(shims) == 0 {
+				pterm.Info.Printfln("No shims in %s", shimDir)
+				return nil
+			}
+			pterm.Success.Printfln("Removed the shims from %s", shimDir)
+			return nil
+		})
+	},
+}
+
+func init() {
+	shimCmd.PersistentFlags().StringVar(&shimDir, "dir", shimDir, "directory of the shims")
+	shimCmd.AddCommand(shimInstallCmd, shimRemoveCmd)
+	rootCmd.AddCommand(shimCmd)
+}
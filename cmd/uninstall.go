@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+)
+
+var uninstallForce bool
+
+type uninstallResult struct {
+	Version   string `json:"version"`
+	Path      string `json:"path"`
+	Freed     int64  `json:"freed"`
+	WasActive bool   `json:"was_active"`
+}
+
+var uninstallCmd = &cobra.Command{
+	Use:     "uninstall <version>",
+	Aliases: []string{"rm"},
+	Short:   "Remove an installed Go toolchain",
+	Long:    "Remove the installed Go toolchain matching the given version query. The active toolchain is only removed with --force.",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installed, err := pkg.InstalledVersions(envsDir())
+		if err != nil {
+			return err
+		}
+		version, err := installed.Resolve(args[0])
+		if err != nil {
+			return errors.Wrapf(err, "no installed toolchain matches %s", args[0])
+		}
+		active := isActive(version.Version)
+		if active && !uninstallForce {
+			return errors.Errorf("%s is the active toolchain, switch to another one first or use --force", version.Version)
+		}
+		dir := pkg.InstallDir(envsDir(), version.Version)
+		size, err := pkg.DirSize(dir)
+		if err != nil {
+			pterm.Debug.Printfln("failed to get size of %s: %s", dir, err)
+		}
+		err = withToolchainLock(version.Version, func() error {
+			// use may have switched to it while the lock was waited for
+			if pkg.CurrentTarget(fsutil.DefaultDataDir) == dir {
+				if !uninstallForce {
+					return errors.Errorf("%s is the active toolchain, switch to another one first or use --force", version.Version)
+				}
+				active = true
+			}
+			return pkg.Uninstall(envsDir(), version.Version)
+		})
+		if err != nil {
+			return err
+		}
+		if active {
+			if err := clearCurrent(); err != nil {
+				return err
+			}
+		}
+		res := uninstallResult{Version: version.Version, Path: dir, Freed: size, WasActive: active}
+		return render("uninstall", res, func() error {
+			pterm.Success.Printfln("Removed %s, freed %s", version.Version, formatSize(size))
+			return nil
+		})
+	},
+}
+
+// clearCurrent removes the current toolchain link and unsets the current version in the config
+func clearCurrent() error {
+	return withCurrentLock(func() error {
+		link := pkg.CurrentLink(fsutil.DefaultDataDir)
+		if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove %s", link)
+		}
+		conf := gomconfig.Get()
+		conf.SetCurrent(nil)
+		return errors.Wrap(conf.Save(), "failed to save config")
+	})
+}
+
+func init() {
+	uninstallCmd.Flags().BoolVarP(&uninstallForce, "force", "f", false, "remove the toolchain even if it's the active one")
+	rootCmd.AddCommand(uninstallCmd)
+}
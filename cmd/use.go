@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg"
+	"github.com/x0f5c3/go-manager/pkg/semver"
+)
+
+type useResult struct {
+	Query    string `json:"query"`
+	Version  string `json:"version"`
+	Previous string `json:"previous,omitempty"`
+	Path     string `json:"path"`
+}
+
+var useCmd = &cobra.Command{
+	Use:     "use <version>",
+	Short:   "Switch the active Go toolchain",
+	Long:    "Switch the active Go toolchain to the newest installed one matching the given version query, see install for the query format.",
+	Example: "go-manager use 1.21\ngo-manager use latest",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		installed, err := pkg.InstalledVersions(envsDir())
+		if err != nil {
+			return err
+		}
+		version, err := installed.Resolve(args[0])
+		if err != nil {
+			return errors.Wrapf(err, "no installed toolchain matches %s, install it first", args[0])
+		}
+		parsed, err := semver.ParseRelease(version.Version)
+		if err != nil {
+			return err
+		}
+		dir := pkg.InstallDir(envsDir(), version.Version)
+		var previous string
+		// the toolchain lock keeps uninstall and prune from removing it until the link points at it
+		err = withToolchainLock(version.Version, func() error {
+			if _, err := os.Stat(dir); err != nil {
+				return errors.Wrapf(err, "%s was removed", version.Version)
+			}
+			return withCurrentLock(func() error {
+				previous, err = pkg.SwitchCurrent(pkg.CurrentLink(fsutil.DefaultDataDir), dir)
+				if err != nil {
+					return err
+				}
+				conf := gomconfig.Get()
+				if previous == "" && conf.Current != nil {
+					previous = conf.Current.String()
+				}
+				conf.SetCurrent(parsed)
+				return errors.Wrap(conf.Save(), "failed to save config")
+			})
+		})
+		if err != nil {
+			return err
+		}
+		if previous != "" {
+			previous = filepath.Base(previous)
+		}
+		res := useResult{Query: args[0], Version: version.Version, Previous: previous, Path: dir}
+		return render("use", res, func() error {
+			if previous == "" {
+				pterm.Success.Printfln("Now using %s", version.Version)
+			} else {
+				pterm.Success.Printfln("Switched from %s to %s", previous, version.Version)
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(useCmd)
+}
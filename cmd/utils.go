@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/pterm/pterm"
+
+	gomconfig "github.com/x0f5c3/go-manager/internal/config"
+	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg/semver"
 )
 
 func checkErr(err error) {
@@ -12,3 +17,44 @@ func checkErr(err error) {
 		os.Exit(1)
 	}
 }
+
+// envsDir returns the directory toolchains are installed to
+func envsDir() string {
+	if conf := gomconfig.Get(); conf != nil && conf.EnvsDir != "" {
+		return conf.EnvsDir
+	}
+	return fsutil.DefaultEnvDir
+}
+
+// withToolchainLock runs fn holding the lock of the toolchain for version, taken by everything installing or removing it
+func withToolchainLock(version string, fn func() error) error {
+	return fsutil.WithLock(fsutil.LockPath(fsutil.DefaultDataDir, version), lockTimeout, fn)
+}
+
+// withCurrentLock runs fn holding the lock of the current toolchain link and the current version in the config
+func withCurrentLock(fn func() error) error {
+	return fsutil.WithLock(fsutil.LockPath(fsutil.DefaultDataDir, "current"), lockTimeout, fn)
+}
+
+// isActive reports whether the go version is the one set as current in the config
+func isActive(version string) bool {
+	conf := gomconfig.Get()
+	if conf == nil || conf.Current == nil {
+		return false
+	}
+	return semver.CompareGo(version, conf.Current.String()) == 0
+}
+
+// formatSize formats a byte count for humans
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -22,7 +22,6 @@ import (
 	"github.com/x0f5c3/go-manager/pkg/semver"
 )
 
-
 var initCmd = &cobra.Command{
 	Use: "init [data directory]",
 	Args: func(cmd *cobra.Command, args []string) error {
@@ -36,10 +35,12 @@ var initCmd = &cobra.Command{
 	},
 	Short: "Initialize config",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		dataDir := args[0]
-		err := setupDataDir(dataDir)
-
-	}
+		dataDir := fsutil.DefaultDataDir
+		if len(args) > 0 {
+			dataDir = args[0]
+		}
+		return setupDataDir(dataDir)
+	},
 }
 
 var setCmd = &cobra.Command{
@@ -56,8 +57,6 @@ var getCmd = &cobra.Command{
 
 var flagSet = configFlagSet()
 
-
-
 func setupDataDir(datadir string) error {
 	if !fsutil.CheckExists(datadir) {
 		err := fsutil.CreateDir(datadir)
@@ -110,7 +109,7 @@ func decoderHookSemver() mapstructure.DecodeHookFuncType {
 		v, ok := data.(string)
 		if f.Kind() == reflect.String && t == reflect.TypeOf(&semver.Version{}) && ok {
 			if semver.IsValid(v) {
-				return semver.Parse(v), nil
+				return semver.Parse(v)
 			}
 		}
 		return data, nil
@@ -5,9 +5,10 @@ import (
 	"io/fs"
 	"os"
 	"os/exec"
-	"reflect"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mitchellh/mapstructure"
@@ -22,20 +23,29 @@ import (
 
 var Conf = defaultConfig()
 
+// DefaultIndexTTL is how long the cached version index is used by default
+const DefaultIndexTTL = time.Hour
+
+// LockTimeout is how long Save waits for other processes saving the config, see fsutil.Lock
+var LockTimeout = fsutil.DefaultLockTimeout
+
+// DefaultCacheSize is the default size cap of the download cache, enough for a dozen releases
+const DefaultCacheSize = "1GiB"
+
 func decoderHookSemver() mapstructure.DecodeHookFuncType {
-	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
-		v, ok := data.(string)
-		if f.Kind() == reflect.String && t == reflect.TypeOf(&semver.Version{}) && ok {
-			if semver.IsValid(v) {
-				return semver.Parse(v), nil
-			}
-		}
-		return data, nil
-	}
+	return semver.DecodeHook()
 }
 
-func currentVersion() (*semver.Version, error) {
+// skipDetectEnv is set for the go command run by currentVersion.
+// If that go is a go-manager shim, its config is loaded without running go again, which would never end.
+const skipDetectEnv = "GOM_SKIP_DETECT"
+
+func currentVersion() (*semver.Release, error) {
+	if os.Getenv(skipDetectEnv) != "" {
+		return nil, errors.New("go version detection skipped in a nested go command")
+	}
 	c := exec.Command("go", "version")
+	c.Env = append(os.Environ(), skipDetectEnv+"=1")
 	out, err := c.Output()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get go version")
@@ -44,14 +54,9 @@ func currentVersion() (*semver.Version, error) {
 	log.Debug().Str("out", string(out)).Msg("go version")
 	sp := strings.Split(string(out), " ")
 	if len(sp) < 3 {
-		return nil, err
-	}
-	res := strings.ReplaceAll(sp[2], "go", "v")
-	if semver.IsValid(res) {
-		return semver.Parse(res)
-	} else {
-		return nil, errors.New("invalid version")
+		return nil, errors.New("invalid go version output")
 	}
+	return semver.ParseRelease(sp[2])
 }
 
 type Config struct {
@@ -59,8 +64,18 @@ type Config struct {
 	LastUpdate time.Time       `mapstructure:"last_update, omitempty"`
 	ConfigFile string          `mapstructure:"config_file, omitempty"`
 	EnvsDir    string          `mapstructure:"envs_dir"`
-	Current    *semver.Version `mapstructure:"current"`
-	mod        bool            `mapstructure:"-"`
+	Current    *semver.Release `mapstructure:"current"`
+	Pinned     []string        `mapstructure:"pinned, omitempty"`
+	IndexTTL   string          `mapstructure:"index_ttl"`
+	Mirror     string          `mapstructure:"mirror, omitempty"`
+	FeedURL    string          `mapstructure:"feed_url, omitempty"`
+	CacheDir   string          `mapstructure:"cache_dir, omitempty"`
+	CacheSize  string          `mapstructure:"cache_max_size"`
+	// AutoInstall installs missing toolchains on first use by a shim or exec
+	AutoInstall bool `mapstructure:"auto_install"`
+	// ActiveEnv is the name of the active GOPATH environment, see env activate
+	ActiveEnv string `mapstructure:"active_env, omitempty"`
+	mod       bool   `mapstructure:"-"`
 }
 
 func (c *Config) SetProxies(Proxies []string) {
@@ -83,11 +98,79 @@ func (c *Config) SetEnvsDir(EnvsDir string) {
 	c.EnvsDir = EnvsDir
 }
 
-func (c *Config) SetCurrent(Current *semver.Version) {
+func (c *Config) SetCurrent(Current *semver.Release) {
 	c.mod = true
 	c.Current = Current
 }
 
+func (c *Config) SetPinned(Pinned []string) {
+	c.mod = true
+	c.Pinned = Pinned
+}
+
+func (c *Config) SetIndexTTL(IndexTTL string) {
+	c.mod = true
+	c.IndexTTL = IndexTTL
+}
+
+func (c *Config) SetMirror(Mirror string) {
+	c.mod = true
+	c.Mirror = Mirror
+}
+
+func (c *Config) SetFeedURL(FeedURL string) {
+	c.mod = true
+	c.FeedURL = FeedURL
+}
+
+func (c *Config) SetCacheDir(CacheDir string) {
+	c.mod = true
+	c.CacheDir = CacheDir
+}
+
+func (c *Config) SetCacheSize(CacheSize string) {
+	c.mod = true
+	c.CacheSize = CacheSize
+}
+
+func (c *Config) SetAutoInstall(AutoInstall bool) {
+	c.mod = true
+	c.AutoInstall = AutoInstall
+}
+
+func (c *Config) SetActiveEnv(ActiveEnv string) {
+	c.mod = true
+	c.ActiveEnv = ActiveEnv
+}
+
+// DownloadCacheDir returns the directory of the shared download cache
+func (c *Config) DownloadCacheDir() string {
+	if c.CacheDir != "" {
+		return c.CacheDir
+	}
+	return filepath.Join(fsutil.DefaultDataDir, "cache")
+}
+
+// CacheMaxSize returns the size the download cache is trimmed to in bytes, 0 means unlimited
+func (c *Config) CacheMaxSize() int64 {
+	size, err := ParseSize(c.CacheSize)
+	if err != nil {
+		log.Debug().Err(err).Str("cache_max_size", c.CacheSize).Msg("invalid cache size, using default")
+		size, _ = ParseSize(DefaultCacheSize)
+	}
+	return size
+}
+
+// IndexCacheTTL returns how long the cached version index is used before it's revalidated
+func (c *Config) IndexCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(c.IndexTTL)
+	if err != nil {
+		log.Debug().Err(err).Str("index_ttl", c.IndexTTL).Msg("invalid index ttl, using default")
+		return DefaultIndexTTL
+	}
+	return ttl
+}
+
 func checkFlagsExists(flags *pflag.FlagSet) []string {
 	var flagNames []string
 	if !flags.Changed("proxies") {
@@ -145,8 +228,6 @@ func ReadInOrFlags(flags *pflag.FlagSet, confDir ...string) (*Config, error) {
 		log.Error().Err(err).Msg("Failed to read config")
 	}
 	changed := checkFlagsExists(flags)
-	current, envsDir, conf := fileConf.Current, fileConf.EnvsDir, fileConf.ConfigFile
-	proxies := fileConf.Proxies
 	if len(changed) > 0 {
 		log.Debug().Strs("flags", changed).Msg("Flags set, using flags")
 		for _, flag := range changed {
@@ -169,8 +250,8 @@ func ReadInOrFlags(flags *pflag.FlagSet, confDir ...string) (*Config, error) {
 				if err != nil {
 					finalConf.Current = fileConf.Current
 				}
-				if semver.IsValid(current) {
-					finalConf.Current, err = semver.Parse(current)
+				if semver.IsValidRelease(current) {
+					finalConf.Current, err = semver.ParseRelease(current)
 					if err != nil {
 						finalConf.Current = fileConf.Current
 					}
@@ -229,13 +310,48 @@ func ReadInConfig(confDir ...string) (*Config, error) {
 	// }
 }
 
+// Values returns the settings by their keys in the config file
+func (c *Config) Values() map[string]interface{} {
+	return map[string]interface{}{
+		"proxies":        c.Proxies,
+		"last_update":    c.LastUpdate,
+		"config_file":    c.ConfigFile,
+		"envs_dir":       c.EnvsDir,
+		"current":        c.Current,
+		"pinned":         c.Pinned,
+		"index_ttl":      c.IndexTTL,
+		"mirror":         c.Mirror,
+		"feed_url":       c.FeedURL,
+		"cache_dir":      c.CacheDir,
+		"cache_max_size": c.CacheSize,
+		"auto_install":   c.AutoInstall,
+		"active_env":     c.ActiveEnv,
+	}
+}
+
 func (c *Config) Save() error {
 	c.LastUpdate = time.Now()
 	viper.Set("last_update", c.LastUpdate)
 	viper.Set("proxies", c.Proxies)
 	viper.Set("envs_dir", c.EnvsDir)
 	viper.Set("current", c.Current)
-	return viper.WriteConfigAs(c.ConfigFile)
+	viper.Set("pinned", c.Pinned)
+	viper.Set("index_ttl", c.IndexTTL)
+	viper.Set("mirror", c.Mirror)
+	viper.Set("feed_url", c.FeedURL)
+	viper.Set("cache_dir", c.CacheDir)
+	viper.Set("cache_max_size", c.CacheSize)
+	viper.Set("auto_install", c.AutoInstall)
+	viper.Set("active_env", c.ActiveEnv)
+	// a config file written without config_file is saved where it's read from by default
+	path := c.ConfigFile
+	if path == "" {
+		path = fsutil.DefaultConfigPath
+	}
+	// other processes may be saving at the same time
+	return fsutil.WithLock(fsutil.LockPath(fsutil.DefaultDataDir, "config"), LockTimeout, func() error {
+		return viper.WriteConfigAs(path)
+	})
 }
 
 func tryRead(toTry ...string) (*Config, error) {
@@ -331,7 +447,39 @@ func tryReadFile(path string) (*Config, error) {
 
 var config = defaultConfig()
 
-func init() {
+var (
+	loadOnce sync.Once
+	readOnly bool
+)
+
+// SetReadOnly makes Get load the config without creating or saving it, taking the config lock or running go
+// to detect the current version, for the shims and the prompt hook running on every go command and prompt.
+// It has to be called before the first Get.
+func SetReadOnly() {
+	readOnly = true
+}
+
+// Get returns the config, it's loaded on first use
+func Get() *Config {
+	loadOnce.Do(load)
+	return config
+}
+
+// load loads the config for Get, creating and saving it unless it's read-only
+func load() {
+	if readOnly {
+		res, err := readConfig(Vip)
+		var notFound viper.ConfigFileNotFoundError
+		if errors.As(err, &notFound) {
+			return
+		}
+		if err != nil {
+			log.Debug().Err(err).Msg("Failed to read config, using defaults")
+			return
+		}
+		config = res
+		return
+	}
 	create, err := configDirCreate()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create config dir")
@@ -343,6 +491,8 @@ func init() {
 		return
 	}
 	config = res
+	// the read only config isn't watched, it's only read on every prompt and go command
+	Vip.WatchConfig()
 	err = config.Save()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to save config")
@@ -363,40 +513,14 @@ func configDirCreate() (string, error) {
 	return fsutil.DefaultDataDir, nil
 }
 
-func mustWriteConfig() {
-	err := Vip.WriteConfig()
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to write config")
-	}
-}
-
-func mustInitConfig() {
-	conf, err := InitConfig(fsutil.DefaultDataDir, WithDefaults())
-	if err != nil {
-		config = defaultConfig()
-
-	}
-	config = conf
-	err = config.Save()
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to save config")
-	}
-}
-
 func defaultConfig() *Config {
-	curr, err := currentVersion()
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get current version, using nil")
-		curr = nil
-	} else {
-		log.Debug().Str("Version", curr.String()).Msg("Got current version")
-	}
 	return &Config{
 		Proxies:    nil,
 		EnvsDir:    fsutil.DefaultEnvDir,
 		ConfigFile: fsutil.DefaultConfigPath,
 		LastUpdate: time.Now(),
-		Current:    curr,
+		IndexTTL:   DefaultIndexTTL.String(),
+		CacheSize:  DefaultCacheSize,
 		mod:        false,
 	}
 }
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMirrorEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantMirror  string
+		wantFeedURL string
+	}{
+		{"unset", nil, "", ""},
+		{"mirror", map[string]string{"GOM_MIRROR": "http://127.0.0.1:8080/dl/"}, "http://127.0.0.1:8080/dl/", ""},
+		{"feed url", map[string]string{"GOM_FEED_URL": "http://127.0.0.1:8080/feed.json"}, "", "http://127.0.0.1:8080/feed.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOM_MIRROR", "")
+			t.Setenv("GOM_FEED_URL", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			v := viper.New()
+			WithDefaults()(v)
+			var c Config
+			if err := v.Unmarshal(&c, viper.DecodeHook(decoderHookSemver())); err != nil {
+				t.Fatal(err)
+			}
+			if c.Mirror != tt.wantMirror || c.FeedURL != tt.wantFeedURL {
+				t.Errorf("Mirror, FeedURL = %q, %q, want %q, %q", c.Mirror, c.FeedURL, tt.wantMirror, tt.wantFeedURL)
+			}
+		})
+	}
+}
+
+// fakeGo puts a go on PATH leaving the returned file behind when it's run
+func fakeGo(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go is a shell script")
+	}
+	dir := t.TempDir()
+	ran := filepath.Join(dir, "ran")
+	if err := os.WriteFile(filepath.Join(dir, "go"), []byte("#!/bin/sh\ntouch '"+ran+"'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv(skipDetectEnv, "")
+	return ran
+}
+
+func TestReadConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		file            string
+		wantAutoInstall bool
+		wantErr         bool
+	}{
+		{"saved", "last_update = 2023-01-02T03:04:05Z\nauto_install = true\nmirror = 'http://127.0.0.1/'\n", true, false},
+		{"never saved", "auto_install = true\n", true, false},
+		{"missing", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := fakeGo(t)
+			dir := t.TempDir()
+			path := filepath.Join(dir, "gom.toml")
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			v := viper.New()
+			v.SetConfigName("gom")
+			v.AddConfigPath(dir)
+			c, err := readConfig(v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && c.AutoInstall != tt.wantAutoInstall {
+				t.Errorf("readConfig().AutoInstall = %v, want %v", c.AutoInstall, tt.wantAutoInstall)
+			}
+			if _, err := os.Stat(ran); err == nil {
+				t.Error("go was run to detect the current version")
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := os.ReadFile(path)
+			if len(entries) > 1 || string(b) != tt.file {
+				t.Errorf("readConfig() wrote to the config dir, it has %v and %q", entries, b)
+			}
+		})
+	}
+}
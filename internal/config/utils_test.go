@@ -0,0 +1,57 @@
+package config
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{size: "0", want: 0},
+		{size: "1024", want: 1024},
+		{size: "512b", want: 512},
+		{size: "500MB", want: 500 * 1000 * 1000},
+		{size: "500 MiB", want: 500 << 20},
+		{size: "2GiB", want: 2 << 30},
+		{size: "1.5g", want: 3 << 29},
+		{size: " 1TB ", want: 1000 * 1000 * 1000 * 1000},
+		{size: "1k", want: 1 << 10},
+		{size: "", wantErr: true},
+		{size: "GiB", wantErr: true},
+		{size: "1PB", wantErr: true},
+		{size: "-1GiB", wantErr: true},
+		{size: "1.2.3MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := ParseSize(tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.size, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheMaxSize(t *testing.T) {
+	tests := []struct {
+		size string
+		want int64
+	}{
+		{"", 1 << 30},
+		{"invalid", 1 << 30},
+		{"0", 0},
+		{"10MiB", 10 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			c := &Config{CacheSize: tt.size}
+			if got := c.CacheMaxSize(); got != tt.want {
+				t.Errorf("CacheMaxSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,12 +12,13 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/pkg/errors"
-	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/x0f5c3/zerolog/log"
 
 	"github.com/x0f5c3/go-manager/internal/fsutil"
+	"github.com/x0f5c3/go-manager/pkg/progress"
+	"github.com/x0f5c3/go-manager/pkg/semver"
 )
 
 type FactoryCommand struct {
@@ -84,7 +85,7 @@ type AppFactory struct {
 
 func NewConfigFactory() *AppFactory {
 	f := &AppFactory{
-		v: commonViper(),
+		v: watchedViper(),
 		cmd: &cobra.Command{
 			Use:   "config",
 			Short: "Manage config",
@@ -173,25 +174,21 @@ func (c *AppFactory) Save() error {
 }
 
 func LoadConfig(path string) (*Config, error) {
-	pb, err := pterm.DefaultSpinner.WithText("Loading config").WithShowTimer(true).WithTimerRoundingFactor(time.Second).Start()
-	if err != nil {
-		log.Error().Err(err).Msg("Error starting spinner")
-	}
+	task := progress.Default().Start(progress.OpConfig, "Loading config", 0)
 	b, err := os.ReadFile(path)
 	if err != nil {
-		pb.Fail(pterm.Red(fmt.Sprintf("Failed to read config from %s", path)))
+		task.Fail(errors.Wrapf(err, "failed to read config from %s", path))
 		return nil, err
 	}
-	pb.Success(pterm.Green(fmt.Sprintf("Read config from %s", path)))
 	log.Info().Str("path", path).Msg("Read config from path, trying to deserialize")
 	var conf Config
 	err = toml.Unmarshal(b, &conf)
 	if err != nil {
-		pb.Fail(pterm.Red("Failed to deserialize config"))
+		task.Fail(errors.Wrap(err, "failed to deserialize config"))
 		log.Error().Err(err).Msg("Failed to deserialize config")
 		return nil, err
 	}
-	pb.Success(pterm.Green("Deserialized config"))
+	task.Done(fmt.Sprintf("Read config from %s", path))
 	return &conf, nil
 }
 
@@ -249,6 +246,15 @@ func setViperDefaults(v *viper.Viper, conf *Config) {
 	v.SetDefault("proxies", conf.Proxies)
 	v.SetDefault("envs_dir", conf.EnvsDir)
 	v.SetDefault("current", conf.Current)
+	v.SetDefault("pinned", conf.Pinned)
+	v.SetDefault("index_ttl", conf.IndexTTL)
+	// Also makes GOM_MIRROR and GOM_FEED_URL visible through AutomaticEnv
+	v.SetDefault("mirror", conf.Mirror)
+	v.SetDefault("feed_url", conf.FeedURL)
+	v.SetDefault("cache_dir", conf.CacheDir)
+	v.SetDefault("cache_max_size", conf.CacheSize)
+	v.SetDefault("auto_install", conf.AutoInstall)
+	v.SetDefault("active_env", conf.ActiveEnv)
 	// current, err := currentVersion()
 	// if err != nil {
 	// 	v.SetDefault("current", nil)
@@ -282,7 +288,6 @@ func commonViper() *viper.Viper {
 		log.Info().Str("event", in.String()).Msg("Config file changed")
 		config.LastUpdate = time.Now()
 	})
-	v.WatchConfig()
 	v.SetConfigName("gom")
 	v.AddConfigPath(fsutil.DefaultDataDir)
 	v.AddConfigPath(filepath.Join(".", "gom"))
@@ -292,12 +297,20 @@ func commonViper() *viper.Viper {
 	return v
 }
 
+// watchedViper returns a common viper watching the config file for changes
+func watchedViper() *viper.Viper {
+	v := commonViper()
+	v.WatchConfig()
+	return v
+}
+
 func InitConfigManual(confDir string, v *viper.Viper) (*Config, error) {
 	v.AddConfigPath(filepath.Join(confDir, "gom"))
 	v.AddConfigPath(confDir)
 	// v.SetDefault("envs_dir", fsutil.DefaultEnvDir)
 	current, err := currentVersion()
 	if err == nil {
+		log.Debug().Str("Version", current.String()).Msg("Got current version")
 		v.SetDefault("current", current)
 	}
 	err = v.ReadInConfig()
@@ -311,6 +324,10 @@ func viperToConfig(v *viper.Viper) (*Config, error) {
 	if v.GetTime("last_update").IsZero() {
 		conf := defaultConfig()
 		conf.LastUpdate = time.Now()
+		// the go on PATH detected by InitConfigManual
+		if current, ok := v.Get("current").(*semver.Release); ok {
+			conf.Current = current
+		}
 		err := conf.Save()
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to save c")
@@ -325,3 +342,17 @@ func viperToConfig(v *viper.Viper) (*Config, error) {
 	}
 	return &c, nil
 }
+
+// readConfig reads the config file found by v without creating or saving it
+func readConfig(v *viper.Viper) (*Config, error) {
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	err = v.Unmarshal(&c, viper.DecodeHook(decoderHookSemver()))
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,163 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pterm/pterm"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// DefaultLockTimeout is how long Lock waits for another process by default, long enough for a slow download
+const DefaultLockTimeout = 10 * time.Minute
+
+// lockPoll is how often a busy lock is retried
+const lockPoll = 100 * time.Millisecond
+
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// LockDir returns the directory holding the lock files in dataDir
+func LockDir(dataDir string) string {
+	return filepath.Join(dataDir, "locks")
+}
+
+// LockPath returns the path of the lock file called name in dataDir
+func LockPath(dataDir, name string) string {
+	return filepath.Join(LockDir(dataDir), name+".lock")
+}
+
+// FileLock is an advisory lock held on a file, shared with other go-manager processes.
+// The file holds the PID of the process holding the lock.
+type FileLock struct {
+	f *os.File
+}
+
+// Lock takes the lock on the file at path, creating it if needed.
+// While another process holds the lock it's retried until timeout passes, a timeout of 0 waits forever.
+// The lock file is never removed, the OS releases the lock of a process that exits without unlocking.
+// A PID left in the file by such a process is stale, it's reported and replaced once the lock is taken.
+// The lock is released by Unlock, or when the process exits.
+func Lock(path string, timeout time.Duration) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s directory", filepath.Dir(path))
+	}
+	start := time.Now()
+	waiting := 0
+	for {
+		f, locked, err := tryLock(path)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			if pid := readPid(f); pid > 0 && pid != os.Getpid() && !processAlive(pid) {
+				log.Warn().Str("Path", path).Int("Pid", pid).Msg("taking over a stale lock left by a process that's no longer running")
+			}
+			if err := writePid(f); err != nil {
+				closeLock(f)
+				return nil, err
+			}
+			log.Debug().Str("Path", path).Dur("Waited", time.Since(start)).Msg("locked")
+			return &FileLock{f: f}, nil
+		}
+		pid := readPid(f)
+		closeLock(f)
+		holder := describeHolder(pid)
+		if pid > 0 && pid != waiting {
+			pterm.Info.Printfln("waiting for lock %s %s", path, holder)
+			waiting = pid
+		}
+		if timeout > 0 && time.Since(start) > timeout {
+			if pid > 0 {
+				return nil, errors.Wrapf(ErrLockTimeout, "%s %s after %s", path, holder, timeout)
+			}
+			return nil, errors.Wrapf(ErrLockTimeout, "%s after %s", path, timeout)
+		}
+		time.Sleep(lockPoll)
+	}
+}
+
+// describeHolder describes the holder of a busy lock from the PID in its file.
+// The PID is stale if that process is gone, the lock is then held by one that hasn't written its PID yet,
+// or by a child that inherited it. Only the OS releases the lock, so it's waited for either way.
+func describeHolder(pid int) string {
+	if pid > 0 && !processAlive(pid) {
+		return fmt.Sprintf("held by another process, its pid %d is stale", pid)
+	}
+	return fmt.Sprintf("held by pid %d", pid)
+}
+
+// tryLock opens the lock file and tries to lock it without blocking, the file is returned open either way
+func tryLock(path string) (*os.File, bool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to open the lock %s", path)
+	}
+	locked, err := lockFile(f)
+	if err != nil {
+		closeLock(f)
+		return nil, false, errors.Wrapf(err, "failed to lock %s", path)
+	}
+	return f, locked, nil
+}
+
+func writePid(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return errors.Wrapf(err, "failed to write the lock %s", f.Name())
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return errors.Wrapf(err, "failed to write the lock %s", f.Name())
+	}
+	return nil
+}
+
+// readPid returns the PID in the lock file, or 0 if it's not written yet
+func readPid(f *os.File) int {
+	b := make([]byte, 32)
+	n, _ := f.ReadAt(b, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+func closeLock(f *os.File) {
+	if err := f.Close(); err != nil {
+		log.Error().Err(err).Msgf("failed to close %s", f.Name())
+	}
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	// clear the PID first, the file is left in place for the next process
+	err := l.f.Truncate(0)
+	if unlockErr := unlockFile(l.f); err == nil {
+		err = unlockErr
+	}
+	if closeErr := l.f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to unlock %s", l.f.Name())
+	}
+	log.Debug().Str("Path", l.f.Name()).Msg("unlocked")
+	return nil
+}
+
+// WithLock runs fn while holding the lock on the file at path, see Lock
+func WithLock(path string, timeout time.Duration, fn func() error) error {
+	lock, err := Lock(path, timeout)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	if unlockErr := lock.Unlock(); err == nil {
+		err = unlockErr
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		held    bool
+		pid     string
+		wantErr string
+	}{
+		{name: "free"},
+		{name: "held", held: true, wantErr: "held by pid " + strconv.Itoa(os.Getpid())},
+		{name: "held with a dead pid", held: true, pid: "999999999\n", wantErr: "held by another process, its pid 999999999 is stale"},
+		{name: "held without a pid", held: true, pid: "\n", wantErr: "after"},
+		{name: "free with a dead pid", pid: "999999999\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := LockPath(t.TempDir(), "go1.21.5")
+			if tt.held {
+				holder, err := Lock(path, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer holder.Unlock()
+			}
+			if tt.pid != "" {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.pid), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before, err := os.Stat(path)
+			if err != nil && tt.held {
+				t.Fatal(err)
+			}
+			lock, err := Lock(path, 3*lockPoll)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				// a stale PID is replaced by ours
+				if b, err := os.ReadFile(path); err != nil || strings.TrimSpace(string(b)) != strconv.Itoa(os.Getpid()) {
+					t.Errorf("lock file holds %q, %v, want our pid", b, err)
+				}
+				if err := lock.Unlock(); err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLockTimeout) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Lock() error = %v, want %s", err, tt.wantErr)
+			}
+			after, err := os.Stat(path)
+			if err != nil || !os.SameFile(before, after) {
+				t.Errorf("the held lock file was replaced")
+			}
+		})
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "current.lock")
+	want := errors.New("failed")
+	err := WithLock(path, time.Second, func() error {
+		if _, err := Lock(path, lockPoll); !errors.Is(err, ErrLockTimeout) {
+			t.Errorf("Lock() inside WithLock error = %v, want ErrLockTimeout", err)
+		}
+		return want
+	})
+	if err != want {
+		t.Errorf("WithLock() error = %v, want %v", err, want)
+	}
+	lock, err := Lock(path, lockPoll)
+	if err != nil {
+		t.Fatalf("Lock() after WithLock error = %v", err)
+	}
+	_ = lock.Unlock()
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package fsutil
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/zerolog/log"
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is where the locked byte lies, past the PID so other processes can still read it
+const lockOffset = 1 << 30
+
+// stillActive is the exit code of a process that's still running
+const stillActive = 259
+
+// lockFile locks f without blocking, it reports false if another process holds the lock
+func lockFile(f *os.File) (bool, error) {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &windows.Overlapped{Offset: lockOffset})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{Offset: lockOffset})
+}
+
+// processAlive reports whether a process with the given PID is running
+func processAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// access denied means it exists
+		return errors.Is(err, windows.ERROR_ACCESS_DENIED)
+	}
+	defer func(h windows.Handle) {
+		if err := windows.CloseHandle(h); err != nil {
+			log.Error().Err(err).Msgf("failed to close the handle of pid %d", pid)
+		}
+	}(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
@@ -3,5 +3,7 @@ package main
 import "github.com/x0f5c3/go-manager/cmd"
 
 func main() {
+	// Started through a go or gofmt shim, run the toolchain instead
+	cmd.ExecShim()
 	cmd.Execute()
 }
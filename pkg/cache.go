@@ -0,0 +1,248 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// DownloadCache is a content-addressed store of verified release archives, keyed by their SHA-256.
+// Every archive is kept as <dir>/<sha256>/<filename>, so a cache directory can be shared between users and installs.
+type DownloadCache struct {
+	Dir string
+	// MaxSize is the total size the cache is trimmed to after adding an archive, 0 means unlimited
+	MaxSize int64
+}
+
+// CacheEntry is an archive in the download cache
+type CacheEntry struct {
+	Sha256   string    `json:"sha256"`
+	Filename string    `json:"filename"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+func NewDownloadCache(dir string, maxSize int64) *DownloadCache {
+	return &DownloadCache{Dir: dir, MaxSize: maxSize}
+}
+
+func (c *DownloadCache) entry(sha string) (*CacheEntry, error) {
+	dir := filepath.Join(c.Dir, sha)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if !f.Type().IsRegular() {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+		return &CacheEntry{
+			Sha256:   sha,
+			Filename: f.Name(),
+			Path:     filepath.Join(dir, f.Name()),
+			Size:     info.Size(),
+			LastUsed: info.ModTime(),
+		}, nil
+	}
+	return nil, errors.Wrap(fs.ErrNotExist, dir)
+}
+
+// Entries returns the archives in the cache, least recently used first
+func (c *DownloadCache) Entries() ([]CacheEntry, error) {
+	dirs, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", c.Dir)
+	}
+	var res []CacheEntry
+	for _, d := range dirs {
+		// skips temporary entries and anything else that isn't named after a checksum
+		if !d.IsDir() || len(d.Name()) != hex.EncodedLen(sha256.Size) {
+			continue
+		}
+		e, err := c.entry(d.Name())
+		if err != nil {
+			log.Warn().Err(err).Msgf("skipping invalid cache entry %s", d.Name())
+			continue
+		}
+		res = append(res, *e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastUsed.Before(res[j].LastUsed)
+	})
+	return res, nil
+}
+
+// Has reports whether the archive with the given checksum is cached
+func (c *DownloadCache) Has(sha string) bool {
+	if sha == "" {
+		return false
+	}
+	_, err := c.entry(sha)
+	return err == nil
+}
+
+// Get copies the cached archive with the given checksum to dest.
+// It returns false if the archive isn't cached, a cached archive that fails verification is removed.
+func (c *DownloadCache) Get(sha string, dest string) (bool, error) {
+	if sha == "" {
+		return false, nil
+	}
+	e, err := c.entry(sha)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := verifySha256(e.Path, sha); err != nil {
+		log.Warn().Err(err).Msg("removing corrupt cache entry")
+		return false, c.Remove(*e)
+	}
+	if err := linkOrCopy(e.Path, dest); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if err := os.Chtimes(e.Path, now, now); err != nil {
+		log.Debug().Err(err).Msgf("failed to mark %s as used", e.Path)
+	}
+	log.Debug().Str("Sha256", sha).Str("Path", e.Path).Msg("using cached archive")
+	return true, nil
+}
+
+// Put adds the verified archive at path to the cache under its checksum and trims the cache to MaxSize
+func (c *DownloadCache) Put(path string, sha string) error {
+	if sha == "" {
+		return nil
+	}
+	dir := filepath.Join(c.Dir, sha)
+	if _, err := c.entry(sha); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s directory", c.Dir)
+	}
+	// build the entry in a temporary directory so other processes never see a partial archive
+	tmp, err := os.MkdirTemp(c.Dir, "."+sha+".tmp-")
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		removeTemp(tmp)
+		return err
+	}
+	if err := linkOrCopy(path, filepath.Join(tmp, filepath.Base(path))); err != nil {
+		removeTemp(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		removeTemp(tmp)
+		if _, statErr := c.entry(sha); statErr == nil {
+			// another process cached it first
+			return nil
+		}
+		return err
+	}
+	return c.Trim(sha)
+}
+
+// Trim removes the least recently used archives until the cache fits MaxSize, keeping the ones in keep
+func (c *DownloadCache) Trim(keep ...string) error {
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	entries, err := c.Entries()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	kept := make(map[string]bool, len(keep))
+	for _, k := range keep {
+		kept[k] = true
+	}
+	for _, e := range entries {
+		if total <= c.MaxSize {
+			break
+		}
+		if kept[e.Sha256] {
+			continue
+		}
+		log.Debug().Str("Path", e.Path).Msg("evicting archive from the cache")
+		if err := c.Remove(e); err != nil {
+			return err
+		}
+		total -= e.Size
+	}
+	return nil
+}
+
+// Remove deletes an entry from the cache
+func (c *DownloadCache) Remove(e CacheEntry) error {
+	return errors.Wrapf(os.RemoveAll(filepath.Dir(e.Path)), "failed to remove %s from the cache", e.Filename)
+}
+
+// Verify checks every cached archive against its checksum and returns the ones that don't match
+func (c *DownloadCache) Verify() ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var bad []CacheEntry
+	for _, e := range entries {
+		if err := verifySha256(e.Path, e.Sha256); err != nil {
+			log.Debug().Err(err).Msg("cache entry failed verification")
+			bad = append(bad, e)
+		}
+	}
+	return bad, nil
+}
+
+// Older returns the entries that haven't been used for longer than age
+func (c *DownloadCache) Older(age time.Duration) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var res []CacheEntry
+	for _, e := range entries {
+		if time.Since(e.LastUsed) > age {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+// linkOrCopy hard links src to dest, copying it if they're on different filesystems
+func linkOrCopy(src string, dest string) error {
+	if err := os.Link(src, dest); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func(in *os.File) {
+		err := in.Close()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to close %s", src)
+		}
+	}(in)
+	return writeFile(filepath.Dir(dest), dest, in, 0644)
+}
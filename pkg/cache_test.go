@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeArchive writes body to dir/name and returns its path and checksum
+func writeArchive(t *testing.T, dir, name, body string) (string, string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(body))
+	return path, hex.EncodeToString(sum[:])
+}
+
+func TestDownloadCacheGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt bool
+		cached  bool
+		want    bool
+	}{
+		{name: "cached", cached: true, want: true},
+		{name: "not cached"},
+		{name: "corrupt", cached: true, corrupt: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			cache := NewDownloadCache(filepath.Join(t.TempDir(), "cache"), 0)
+			path, sha := writeArchive(t, src, "go1.21.5.linux-amd64.tar.gz", "archive")
+			if tt.cached {
+				if err := cache.Put(path, sha); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.corrupt {
+				if err := os.WriteFile(filepath.Join(cache.Dir, sha, filepath.Base(path)), []byte("corrupt"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dest := filepath.Join(t.TempDir(), filepath.Base(path))
+			got, err := cache.Get(sha, dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Get() = %v, want %v", got, tt.want)
+			}
+			if tt.want {
+				if err := verifySha256(dest, sha); err != nil {
+					t.Error(err)
+				}
+			}
+			if cache.Has(sha) != tt.want {
+				t.Errorf("Has() = %v after Get, want %v", !tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadCacheTrim(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int64
+		// want are the archives left after adding a, b and c of 10 bytes each, used in that order
+		want []string
+	}{
+		{"unlimited", 0, []string{"a", "b", "c"}},
+		{"fits", 30, []string{"a", "b", "c"}},
+		{"least recently used evicted", 20, []string{"b", "c"}},
+		{"added archive kept", 5, []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			cache := NewDownloadCache(filepath.Join(t.TempDir(), "cache"), tt.maxSize)
+			past := time.Now().Add(-time.Hour)
+			for i, name := range []string{"a", "b", "c"} {
+				path, sha := writeArchive(t, src, name, name+"123456789")
+				if err := cache.Put(path, sha); err != nil {
+					t.Fatal(err)
+				}
+				used := past.Add(time.Duration(i) * time.Minute)
+				if err := os.Chtimes(filepath.Join(cache.Dir, sha, name), used, used); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entries, err := cache.Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Filename)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cache has %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadCacheOlderVerify(t *testing.T) {
+	src := t.TempDir()
+	cache := NewDownloadCache(filepath.Join(t.TempDir(), "cache"), 0)
+	oldPath, oldSha := writeArchive(t, src, "old", "old archive")
+	newPath, newSha := writeArchive(t, src, "new", "new archive")
+	for path, sha := range map[string]string{oldPath: oldSha, newPath: newSha} {
+		if err := cache.Put(path, sha); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(cache.Dir, oldSha, "old"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	older, err := cache.Older(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(older) != 1 || older[0].Sha256 != oldSha {
+		t.Errorf("Older() = %v, want only the old archive", older)
+	}
+	if err := os.WriteFile(filepath.Join(cache.Dir, newSha, "new"), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bad, err := cache.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 1 || bad[0].Sha256 != newSha {
+		t.Errorf("Verify() = %v, want only the tampered archive", bad)
+	}
+}
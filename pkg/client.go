@@ -0,0 +1,270 @@
+package pkg
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+	"github.com/x0f5c3/manic-go/pkg/downloader"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// ProxyDialTimeout is how long connecting to a single proxy may take before the next one is tried
+const ProxyDialTimeout = 10 * time.Second
+
+type proxyDialer struct {
+	proxy *url.URL
+	dial  fasthttp.DialFunc
+}
+
+// defaultClient is used when no client is set in the options or download settings
+var defaultClient, _ = NewClient(nil)
+
+// NewClient returns a client for the version index and archive downloads sending requests through the given proxies.
+// Proxies are URLs with an http, https or socks5 scheme and are tried in order until one of them connects.
+// Hosts matched by the NO_PROXY environment variable are connected to directly.
+func NewClient(proxies []string) (*downloader.Client, error) {
+	client := downloader.NewClient()
+	// manic's gnet based dialer fails requests with closed connections or unsupported operation errors
+	// and stalls parallel range requests, see TestNewClient, so dial with the standard library
+	client.Dial = fasthttp.Dial
+	if len(proxies) == 0 {
+		return client, nil
+	}
+	var dialers []proxyDialer
+	for _, p := range proxies {
+		d, err := newProxyDialer(p)
+		if err != nil {
+			return nil, err
+		}
+		dialers = append(dialers, d)
+	}
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+	direct := client.Dial
+	client.Dial = func(addr string) (net.Conn, error) {
+		if matchNoProxy(noProxy, addr) {
+			log.Debug().Str("Addr", addr).Msg("NO_PROXY matched, connecting directly")
+			return direct(addr)
+		}
+		var errs *multierror.Error
+		for _, d := range dialers {
+			conn, err := d.dial(addr)
+			if err == nil {
+				return conn, nil
+			}
+			log.Warn().Err(err).Str("Proxy", d.proxy.Redacted()).Msgf("failed to connect to %s through proxy, trying the next one", addr)
+			errs = multierror.Append(errs, err)
+		}
+		return nil, errors.Wrapf(errs.ErrorOrNil(), "failed to connect to %s through any proxy", addr)
+	}
+	return client, nil
+}
+
+func newProxyDialer(proxy string) (proxyDialer, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return proxyDialer{}, errors.Wrapf(err, "invalid proxy %q", proxy)
+	}
+	if u.Port() == "" {
+		port := map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}[u.Scheme]
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	d := proxyDialer{proxy: u}
+	switch u.Scheme {
+	case "http", "https":
+		d.dial = func(addr string) (net.Conn, error) { return dialConnect(u, addr) }
+	case "socks5", "socks5h":
+		d.dial = func(addr string) (net.Conn, error) { return dialSocks5(u, addr) }
+	default:
+		return proxyDialer{}, errors.Errorf("unsupported proxy scheme %q in %s", u.Scheme, u.Redacted())
+	}
+	return d, nil
+}
+
+// dialConnect opens a tunnel to addr through an http or https proxy using CONNECT
+func dialConnect(proxy *url.URL, addr string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", proxy.Host, ProxyDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if proxy.User != nil {
+		pass, _ := proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + pass))
+		req += "Proxy-Authorization: Basic " + auth + "\r\n"
+	}
+	req += "\r\n"
+	_ = conn.SetDeadline(time.Now().Add(ProxyDialTimeout))
+	if _, err := conn.Write([]byte(req)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	resp.SkipBody = true
+	if err := resp.Read(bufio.NewReader(conn)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		_ = conn.Close()
+		return nil, errors.Errorf("proxy %s refused to connect to %s: %d", proxy.Host, addr, resp.StatusCode())
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// dialSocks5 opens a connection to addr through a socks5 proxy, see RFC 1928 and RFC 1929
+func dialSocks5(proxy *url.URL, addr string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", proxy.Host, ProxyDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(ProxyDialTimeout))
+	fail := func(err error) (net.Conn, error) {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "socks5 proxy %s", proxy.Host)
+	}
+	method := byte(0x00)
+	if proxy.User != nil {
+		method = 0x02
+	}
+	if _, err := conn.Write([]byte{0x05, 0x01, method}); err != nil {
+		return fail(err)
+	}
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return fail(err)
+	}
+	if buf[0] != 0x05 || buf[1] != method {
+		return fail(errors.New("no acceptable authentication method"))
+	}
+	if method == 0x02 {
+		user := proxy.User.Username()
+		pass, _ := proxy.User.Password()
+		msg := append([]byte{0x01, byte(len(user))}, user...)
+		msg = append(append(msg, byte(len(pass))), pass...)
+		if _, err := conn.Write(msg); err != nil {
+			return fail(err)
+		}
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return fail(err)
+		}
+		if buf[1] != 0x00 {
+			return fail(errors.New("authentication failed"))
+		}
+	}
+	req := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+		req = append(append(req, 0x01), ip.To4()...)
+	} else if ip != nil {
+		req = append(append(req, 0x04), ip.To16()...)
+	} else {
+		req = append(append(req, 0x03, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return fail(err)
+	}
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fail(err)
+	}
+	if head[1] != 0x00 {
+		return fail(errors.Errorf("connect to %s failed with code %d", addr, head[1]))
+	}
+	var skip int
+	switch head[3] {
+	case 0x01:
+		skip = net.IPv4len
+	case 0x04:
+		skip = net.IPv6len
+	case 0x03:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return fail(err)
+		}
+		skip = int(buf[0])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip+2)); err != nil {
+		return fail(err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// matchNoProxy reports whether addr should bypass the proxies according to a NO_PROXY style list.
+// Entries can be *, hosts matching themselves and their subdomains, IPs or CIDR ranges, optionally with a port.
+func matchNoProxy(noProxy string, addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		if ip != nil {
+			if entryIP := net.ParseIP(entry); entryIP != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,354 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	pathpkg "path"
+	"sync"
+	"sync/atomic"
+
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+	"github.com/x0f5c3/go-manager/pkg/progress"
+	"github.com/x0f5c3/manic-go/pkg/chunk"
+	"github.com/x0f5c3/manic-go/pkg/downloader"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+const (
+	// downloadWorkers is the number of ranges downloaded in parallel
+	downloadWorkers = 10
+	// rangeSize is the size of a single range request, and the most progress lost when a download is interrupted
+	rangeSize    = 4 << 20
+	maxRedirects = 10
+	// partSuffix is appended to the output path while a download is in progress
+	partSuffix = ".part"
+	// partStateSuffix is appended to the output path for the sidecar recording the downloaded ranges of the .part file
+	partStateSuffix = ".part.json"
+)
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// CancelledError is returned when a download or index fetch is stopped because its context is done.
+// It unwraps to context.Canceled or context.DeadlineExceeded.
+type CancelledError struct {
+	URL string
+	Err error
+}
+
+func (e *CancelledError) Error() string {
+	return fmt.Sprintf("fetching %s cancelled: %v", e.URL, e.Err)
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// IsCancelled reports whether err was caused by a cancelled or timed out context
+func IsCancelled(err error) bool {
+	var cancelled *CancelledError
+	return errors.As(err, &cancelled)
+}
+
+// doContext sends req with client, following redirects, and waits for the response until the deadline of ctx.
+// It takes ownership of req, the returned response has to be released by the caller.
+// fasthttp can't abort a request, so without a deadline a cancelled ctx is only noticed between requests,
+// which is why downloads are split into ranges of at most rangeSize bytes.
+func doContext(ctx context.Context, client *downloader.Client, req *fasthttp.Request) (*fasthttp.Response, error) {
+	defer fasthttp.ReleaseRequest(req)
+	url := req.URI().String()
+	resp := fasthttp.AcquireResponse()
+	fail := func(err error) (*fasthttp.Response, error) {
+		fasthttp.ReleaseResponse(resp)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, &CancelledError{URL: url, Err: ctxErr}
+		}
+		return nil, err
+	}
+	for redirects := 0; ; redirects++ {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		var err error
+		if deadline, ok := ctx.Deadline(); ok {
+			err = client.DoDeadline(req, resp, deadline)
+		} else {
+			err = client.Do(req, resp)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if !fasthttp.StatusCodeIsRedirect(resp.StatusCode()) {
+			return resp, nil
+		}
+		if redirects == maxRedirects {
+			return fail(fasthttp.ErrTooManyRedirects)
+		}
+		location := resp.Header.Peek("Location")
+		if len(location) == 0 {
+			return fail(fasthttp.ErrMissingLocation)
+		}
+		req.URI().UpdateBytes(location)
+		req.SetRequestURI(req.URI().String())
+	}
+}
+
+type byteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+func (r byteRange) len() int64 {
+	return r.End - r.Start + 1
+}
+
+// splitRanges splits size bytes into inclusive ranges of at most rangeSize bytes, using the chunks manic's chunked
+// download requests. The Range header is built from their offset and length, manic's own ends one byte past the chunk.
+func splitRanges(size int64) ([]byteRange, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+	chunks, err := chunk.New(0, int(size-1), rangeSize)
+	if err != nil {
+		return nil, err
+	}
+	var res []byteRange
+	for _, c := range chunks.Collect() {
+		res = append(res, byteRange{Start: int64(c.Offset), End: int64(c.Offset + c.Length - 1)})
+	}
+	return res, nil
+}
+
+// partState is the sidecar of a .part file, recording which ranges of it have been downloaded
+type partState struct {
+	Sha256 string      `json:"sha256"`
+	Size   int64       `json:"size"`
+	Done   []byteRange `json:"done"`
+	path   string
+}
+
+// loadPartState reads the sidecar at path, returning a fresh state if it's missing or belongs to a different file
+func loadPartState(path string, sha string, size int64) *partState {
+	fresh := &partState{Sha256: sha, Size: size, path: path}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Msgf("ignoring unreadable download state %s", path)
+		}
+		return fresh
+	}
+	var state partState
+	if err := json.Unmarshal(b, &state); err != nil {
+		log.Warn().Err(err).Msgf("ignoring corrupt download state %s", path)
+		return fresh
+	}
+	if state.Sha256 != sha || state.Size != size {
+		log.Debug().Str("Path", path).Msg("download state is for a different file, starting over")
+		return fresh
+	}
+	state.path = path
+	return &state
+}
+
+func (s *partState) done(r byteRange) bool {
+	for _, d := range s.Done {
+		if d == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *partState) add(r byteRange) error {
+	s.Done = append(s.Done, r)
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, b, 0644)
+}
+
+// removePartial removes the .part file and sidecar of a download
+func removePartial(paths ...string) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Error().Err(err).Msgf("failed to remove partial download %s", path)
+		}
+	}
+}
+
+// downloadFile downloads url into path like manic's chunked File.Download, fetching its chunks with downloadWorkers
+// parallel range requests of the manic client. Unlike File.Download, which keeps the whole file in memory, takes
+// no context and in v0.5.7 panics writing the chunks into its buffer, every chunk is written to path as it arrives,
+// synced, and recorded in the sidecar at statePath, so an interrupted download resumes where it stopped.
+// A size of 0 or less downloads the file with a single request which can't be resumed.
+// It returns the number of bytes that were already downloaded by a previous attempt.
+func downloadFile(ctx context.Context, client *downloader.Client, reporter progress.Reporter, url string, path string, statePath string, sha string, size int64) (resumed int64, err error) {
+	if size <= 0 {
+		out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return 0, err
+		}
+		err = downloadRange(ctx, client, url, out, nil, nil)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return 0, err
+	}
+	state := loadPartState(statePath, sha, size)
+	flags := os.O_CREATE | os.O_WRONLY
+	if len(state.Done) == 0 {
+		flags |= os.O_TRUNC
+	}
+	out, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := out.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	if err := out.Truncate(size); err != nil {
+		return 0, err
+	}
+	ranges, err := splitRanges(size)
+	if err != nil {
+		return 0, err
+	}
+	var todo []byteRange
+	for _, r := range ranges {
+		if state.done(r) {
+			resumed += r.len()
+		} else {
+			todo = append(todo, r)
+		}
+	}
+	if resumed > 0 {
+		log.Info().Str("Path", path).Msgf("resuming download, %d of %d bytes already downloaded", resumed, size)
+	}
+	task := reporter.Start(progress.OpDownload, "Downloading "+pathpkg.Base(url), size)
+	defer func() {
+		if err != nil {
+			task.Fail(err)
+		} else {
+			task.Done("")
+		}
+	}()
+	task.Add(resumed)
+	var mu sync.Mutex
+	finished := func(r byteRange) error {
+		mu.Lock()
+		defer mu.Unlock()
+		task.Add(r.len())
+		// the range is only recorded once its data is on disk, or a crash could resume with a hole in the file
+		if err := out.Sync(); err != nil {
+			return errors.Wrapf(err, "failed to sync %s", path)
+		}
+		return state.add(r)
+	}
+	queue := make(chan byteRange, len(todo))
+	for _, r := range todo {
+		queue <- r
+	}
+	close(queue)
+	workers := downloadWorkers
+	if len(todo) < workers {
+		workers = len(todo)
+	}
+	// after a failed range the others in flight still finish, so their progress is kept for the next attempt
+	var failed atomic.Bool
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for r := range queue {
+				if failed.Load() {
+					break
+				}
+				r := r
+				if err := downloadRange(ctx, client, url, out, &r, finished); err != nil {
+					failed.Store(true)
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	var first error
+	for i := 0; i < workers; i++ {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return resumed, first
+}
+
+// downloadRange downloads r of url into out, or the whole file if r is nil, and calls finished once it's written
+func downloadRange(ctx context.Context, client *downloader.Client, url string, out io.WriterAt, r *byteRange, finished func(byteRange) error) error {
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(url)
+	req.Header.SetMethod("GET")
+	want := fasthttp.StatusOK
+	if r != nil {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.Start, r.End))
+		want = fasthttp.StatusPartialContent
+	}
+	resp, err := doContext(ctx, client, req)
+	if err != nil {
+		return err
+	}
+	defer fasthttp.ReleaseResponse(resp)
+	body := resp.Body()
+	if r == nil {
+		if resp.StatusCode() != want {
+			return errors.Errorf("failed to fetch %s: %s", url, fasthttp.StatusMessage(resp.StatusCode()))
+		}
+		_, err := out.WriteAt(body, 0)
+		return err
+	}
+	// a server ignoring the range sends the whole file, which is fine if that's all we asked for
+	if resp.StatusCode() == fasthttp.StatusOK && r.Start == 0 && int64(len(body)) == r.len() {
+		want = fasthttp.StatusOK
+	}
+	if resp.StatusCode() != want {
+		return errors.Errorf("failed to fetch %s: %s", url, fasthttp.StatusMessage(resp.StatusCode()))
+	}
+	if int64(len(body)) != r.len() {
+		return errors.Errorf("failed to fetch %s: expected %d bytes at %d, got %d", url, r.len(), r.Start, len(body))
+	}
+	if _, err := out.WriteAt(body, r.Start); err != nil {
+		return err
+	}
+	return finished(*r)
+}
+
+// verifySha256 checks the file at path against the hex encoded sum, an empty sum isn't checked
+func verifySha256(path string, sum string) error {
+	if sum == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to close %s", path)
+		}
+	}(f)
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != sum {
+		return errors.Wrapf(ErrChecksumMismatch, "%s: expected %s, got %s", path, sum, got)
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/x0f5c3/go-manager/pkg/progress"
+)
+
+func TestSplitRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		size int64
+		want []byteRange
+	}{
+		{"empty", 0, nil},
+		{"one byte", 1, []byteRange{{0, 0}}},
+		{"one range", rangeSize, []byteRange{{0, rangeSize - 1}}},
+		{"one byte over", rangeSize + 1, []byteRange{{0, rangeSize - 1}, {rangeSize, rangeSize}}},
+		{"two and a half", 5 * rangeSize / 2, []byteRange{{0, rangeSize - 1}, {rangeSize, 2*rangeSize - 1}, {2 * rangeSize, 5*rangeSize/2 - 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitRanges(tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitRanges(%d) = %v, want %v", tt.size, got, tt.want)
+			}
+			var total int64
+			for _, r := range got {
+				total += r.len()
+			}
+			if total != tt.size {
+				t.Errorf("splitRanges(%d) covers %d bytes", tt.size, total)
+			}
+		})
+	}
+}
+
+func TestLoadPartState(t *testing.T) {
+	done := []byteRange{{0, rangeSize - 1}}
+	tests := []struct {
+		name     string
+		sidecar  string
+		wantDone []byteRange
+	}{
+		{"missing", "", nil},
+		{"corrupt", "{", nil},
+		{"other file", `{"sha256": "other", "size": 10, "done": [{"start": 0, "end": 4194303}]}`, nil},
+		{"other size", `{"sha256": "sum", "size": 11, "done": [{"start": 0, "end": 4194303}]}`, nil},
+		{"same file", `{"sha256": "sum", "size": 10, "done": [{"start": 0, "end": 4194303}]}`, done},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "go.tar.gz"+partStateSuffix)
+			if tt.sidecar != "" {
+				if err := os.WriteFile(path, []byte(tt.sidecar), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			state := loadPartState(path, "sum", 10)
+			if state.Sha256 != "sum" || state.Size != 10 || state.path != path {
+				t.Errorf("loadPartState() = %+v", state)
+			}
+			if !reflect.DeepEqual(state.Done, tt.wantDone) {
+				t.Errorf("loadPartState().Done = %v, want %v", state.Done, tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestPartStateAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go.tar.gz"+partStateSuffix)
+	state := loadPartState(path, "sum", 2*rangeSize)
+	first, second := byteRange{0, rangeSize - 1}, byteRange{rangeSize, 2*rangeSize - 1}
+	if err := state.add(second); err != nil {
+		t.Fatal(err)
+	}
+	loaded := loadPartState(path, "sum", 2*rangeSize)
+	if loaded.done(first) || !loaded.done(second) {
+		t.Errorf("reloaded state = %v, want only %v done", loaded.Done, second)
+	}
+}
+
+// rangeServer serves body, recording the Range header of every request
+type rangeServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	ranges []string
+}
+
+func newRangeServer(t *testing.T, body []byte, delay time.Duration) *rangeServer {
+	t.Helper()
+	s := &rangeServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.mu.Unlock()
+		time.Sleep(delay)
+		http.ServeContent(w, r, "archive", time.Time{}, bytes.NewReader(body))
+	})
+	mux.Handle("/redirect", http.RedirectHandler("/archive", http.StatusFound))
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestDownloadFile(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789abcdef"), (5*rangeSize/2)/16)
+	sum := sha256.Sum256(body)
+	sha := hex.EncodeToString(sum[:])
+	ranges, err := splitRanges(int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		path        string
+		size        int64
+		done        []byteRange
+		delay       time.Duration
+		timeout     time.Duration
+		wantResumed int64
+		wantRanges  int
+		wantErr     bool
+	}{
+		{name: "fresh", path: "/archive", size: int64(len(body)), wantRanges: len(ranges)},
+		{name: "resumed", path: "/archive", size: int64(len(body)), done: ranges[:2], wantResumed: 2 * rangeSize, wantRanges: 1},
+		{name: "unknown size", path: "/archive", wantRanges: 1},
+		{name: "redirect", path: "/redirect", size: int64(len(body)), wantRanges: len(ranges)},
+		{name: "deadline", path: "/archive", size: int64(len(body)), delay: time.Second, timeout: 50 * time.Millisecond, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newRangeServer(t, body, tt.delay)
+			dir := t.TempDir()
+			part, statePath := filepath.Join(dir, "archive"+partSuffix), filepath.Join(dir, "archive"+partStateSuffix)
+			if tt.done != nil {
+				if err := os.WriteFile(part, body[:tt.done[len(tt.done)-1].End+1], 0644); err != nil {
+					t.Fatal(err)
+				}
+				state := loadPartState(statePath, sha, tt.size)
+				for _, r := range tt.done {
+					if err := state.add(r); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			client, err := NewClient(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			resumed, err := downloadFile(ctx, client, progress.Nop, srv.URL+tt.path, part, statePath, sha, tt.size)
+			if tt.wantErr {
+				if !IsCancelled(err) || !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("downloadFile() error = %v, want a CancelledError", err)
+				}
+				if elapsed := time.Since(start); elapsed >= tt.delay {
+					t.Errorf("downloadFile() waited %s for the server after the deadline", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resumed != tt.wantResumed {
+				t.Errorf("downloadFile() resumed = %d, want %d", resumed, tt.wantResumed)
+			}
+			if len(srv.ranges) != tt.wantRanges {
+				t.Errorf("server got %d requests %v, want %d", len(srv.ranges), srv.ranges, tt.wantRanges)
+			}
+			if err := verifySha256(part, sha); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDownloadFileCancelled(t *testing.T) {
+	srv := newRangeServer(t, []byte("archive"), 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dir := t.TempDir()
+	_, err := downloadFile(ctx, defaultClient, progress.Nop, srv.URL+"/archive", filepath.Join(dir, "a"+partSuffix),
+		filepath.Join(dir, "a"+partStateSuffix), "", 7)
+	if !IsCancelled(err) || !errors.Is(err, context.Canceled) {
+		t.Errorf("downloadFile() error = %v, want a CancelledError", err)
+	}
+	if len(srv.ranges) != 0 {
+		t.Errorf("server got %v after the context was cancelled", srv.ranges)
+	}
+}
+
+func TestDownloadFileKilledAndResumed(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789abcdef"), (5*rangeSize/2)/16)
+	sum := sha256.Sum256(body)
+	sha := hex.EncodeToString(sum[:])
+	ranges, err := splitRanges(int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := fmt.Sprintf("bytes=%d-%d", ranges[len(ranges)-1].Start, ranges[len(ranges)-1].End)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		requests []string
+		served   int
+		killed   = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Header.Get("Range"))
+		kill := killed && r.Header.Get("Range") == last
+		mu.Unlock()
+		if kill {
+			// the download is killed once the other ranges are served, dropping the connection of the last one
+			for {
+				mu.Lock()
+				n := served
+				mu.Unlock()
+				if n == len(ranges)-1 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		http.ServeContent(w, r, "archive", time.Time{}, bytes.NewReader(body))
+		mu.Lock()
+		served++
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	part, statePath := filepath.Join(dir, "archive"+partSuffix), filepath.Join(dir, "archive"+partStateSuffix)
+	client, err := NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := downloadFile(ctx, client, progress.Nop, srv.URL, part, statePath, sha, int64(len(body))); !IsCancelled(err) {
+		t.Fatalf("killed downloadFile() error = %v, want a CancelledError", err)
+	}
+	state := loadPartState(statePath, sha, int64(len(body)))
+	sort.Slice(state.Done, func(i, j int) bool { return state.Done[i].Start < state.Done[j].Start })
+	if !reflect.DeepEqual(state.Done, ranges[:len(ranges)-1]) {
+		t.Errorf("recorded ranges = %v, want %v", state.Done, ranges[:len(ranges)-1])
+	}
+	mu.Lock()
+	killed, requests = false, nil
+	mu.Unlock()
+	resumed, err := downloadFile(context.Background(), client, progress.Nop, srv.URL, part, statePath, sha, int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len(body)) - ranges[len(ranges)-1].len(); resumed != want {
+		t.Errorf("downloadFile() resumed = %d, want %d", resumed, want)
+	}
+	if !reflect.DeepEqual(requests, []string{last}) {
+		t.Errorf("resumed download requested %v, want only %s", requests, last)
+	}
+	if err := verifySha256(part, sha); err != nil {
+		t.Error(err)
+	}
+}
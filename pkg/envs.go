@@ -5,7 +5,10 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
 
+	"github.com/pelletier/go-toml/v2"
 	"github.com/pkg/errors"
 	"github.com/pterm/pterm"
 	"github.com/x0f5c3/zerolog/log"
@@ -89,69 +92,259 @@ func createDir(dir string) error {
 	return nil
 }
 
+// writeFileAtomic writes data to a temporary file next to path and renames it into place
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temp file for %s", path)
+	}
+	name := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(name, perm)
+	}
+	if err == nil {
+		err = os.Rename(name, path)
+	}
+	if err != nil {
+		if err := os.Remove(name); err != nil {
+			log.Error().Err(err).Msgf("failed to remove the temp file %s", name)
+		}
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	return nil
+}
+
+// EnvManifest is the file describing a named environment, at the root of its GOPATH
+const EnvManifest = "gom-env.toml"
+
+// namedEnvsDir is the directory of the named environments in the envs dir, apart from the toolchains
+const namedEnvsDir = "gopaths"
+
+var (
+	ErrEnvExists   = errors.New("env already exists")
+	ErrEnvNotFound = errors.New("env not found")
+)
+
+// envNamePattern keeps env names usable as directory names on every platform
+var envNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// GOPATH is a named environment binding a GOPATH, a GOBIN and optionally a go version
 type GOPATH struct {
-	EnvName string
-	Dir     string
-	BinDir  string
-	GOROOT
+	EnvName string    `toml:"name" json:"name"`
+	Dir     string    `toml:"gopath" json:"gopath"`
+	BinDir  string    `toml:"gobin" json:"gobin"`
+	Go      string    `toml:"go,omitempty" json:"go,omitempty"`
+	Created time.Time `toml:"created" json:"created"`
+	// GOROOT is the registry the env belongs to
+	GOROOT `toml:"-" json:"-"`
 }
 
+func (g *GOPATH) GetEnv() string {
+	return g.EnvName
+}
+
+// GOROOT is the registry of the named environments, each one has its own directory holding its manifest.
+// A zero GOROOT is the registry in EnvsDir.
 type GOROOT struct {
 	Dir     string
-	paths   map[string]*GOPATH
 	current *GOPATH
 }
 
-func (g *GOPATH) GetEnv() string {
-	return g.EnvName
+// NewGOROOT returns the registry of the named environments kept in envsDir
+func NewGOROOT(envsDir string) *GOROOT {
+	return &GOROOT{Dir: filepath.Join(envsDir, namedEnvsDir)}
 }
 
+// root returns the directory of the registry
+func (g *GOROOT) root() string {
+	if g.Dir == "" {
+		return filepath.Join(EnvsDir, namedEnvsDir)
+	}
+	return g.Dir
+}
+
+// GetCurrent returns the env set with SetCurrent
+//
+// Deprecated: the active env is saved in the config by the env activate command.
 func (g *GOROOT) GetCurrent() *GOPATH {
 	return g.current
 }
 
+// SetCurrent makes the env envName the one returned by GetCurrent
+//
+// Deprecated: the active env is saved in the config by the env activate command.
+func (g *GOROOT) SetCurrent(envName string) error {
+	env, err := g.GetEnv(envName)
+	if err != nil {
+		return err
+	}
+	g.current = env
+	return nil
+}
+
+// NewEnv creates the env name with its GOPATH in path
+//
+// Deprecated: use CreateEnv, which keeps the GOPATH in the env's directory and binds a go version.
 func (g *GOROOT) NewEnv(name string, path string) error {
-	if g.paths == nil {
-		g.paths = make(map[string]*GOPATH)
+	_, err := g.createEnv(name, path, "")
+	return err
+}
+
+// CreateEnv creates the env name and its manifest, goVersion is the go version bound to it and may be empty
+func (g *GOROOT) CreateEnv(name string, goVersion string) (*GOPATH, error) {
+	return g.createEnv(name, "", goVersion)
+}
+
+// createEnv creates the env name, with its GOPATH in its own directory unless gopath is set
+func (g *GOROOT) createEnv(name string, gopath string, goVersion string) (*GOPATH, error) {
+	if !envNamePattern.MatchString(name) {
+		return nil, errors.Errorf("invalid env name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	if err := os.MkdirAll(g.root(), 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s directory", g.root())
 	}
-	if v, ok := g.paths[name]; ok && v != nil {
-		return errors.New("env already exists")
+	dir := filepath.Join(g.root(), name)
+	// creating the directory itself claims the name
+	if err := os.Mkdir(dir, 0755); errors.Is(err, fs.ErrExist) {
+		return nil, errors.Wrap(ErrEnvExists, name)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s directory", dir)
+	}
+	if gopath == "" {
+		gopath = dir
 	}
-	g.paths[name] = &GOPATH{
+	env := &GOPATH{
 		EnvName: name,
-		Dir:     path,
-		BinDir:  filepath.Join(path, "bin"),
-		GOROOT:  *g,
+		Dir:     gopath,
+		BinDir:  filepath.Join(gopath, "bin"),
+		Go:      goVersion,
+		Created: time.Now().UTC().Truncate(time.Second),
+		GOROOT:  GOROOT{Dir: g.root()},
 	}
-	return nil
+	err := os.MkdirAll(env.BinDir, 0755)
+	if err == nil {
+		err = env.save()
+	}
+	if err != nil {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Error().Err(err).Msgf("failed to remove %s", dir)
+		}
+		return nil, err
+	}
+	return env, nil
 }
 
-func (g *GOROOT) SetCurrent(envName string) error {
-	if g.paths == nil {
-		g.paths = make(map[string]*GOPATH)
-	}
-	if g.paths[envName] == nil {
-		g.paths[envName] = &GOPATH{
-			EnvName: envName,
-			Dir:     filepath.Join(EnvsDir, envName),
-			GOROOT:  *g,
+// manifest returns the path of the manifest of the env
+func (g *GOPATH) manifest() string {
+	return filepath.Join(g.GOROOT.root(), g.EnvName, EnvManifest)
+}
+
+func (g *GOPATH) save() error {
+	b, err := toml.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(g.manifest(), b, 0644)
+}
+
+// GetEnv reads the manifest of the env name
+func (g *GOROOT) GetEnv(name string) (*GOPATH, error) {
+	if !envNamePattern.MatchString(name) {
+		return nil, errors.Wrap(ErrEnvNotFound, name)
+	}
+	path := filepath.Join(g.root(), name, EnvManifest)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.Wrap(ErrEnvNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+	env := &GOPATH{GOROOT: GOROOT{Dir: g.root()}}
+	if err := toml.Unmarshal(b, env); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	return env, nil
+}
+
+// Envs returns the named environments sorted by name, directories without a readable manifest are skipped
+func (g *GOROOT) Envs() ([]*GOPATH, error) {
+	entries, err := os.ReadDir(g.root())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", g.root())
+	}
+	var envs []*GOPATH
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
 		}
+		env, err := g.GetEnv(entry.Name())
+		if err != nil {
+			log.Debug().Err(err).Str("Path", filepath.Join(g.root(), entry.Name())).Msg("not an env, skipping")
+			continue
+		}
+		envs = append(envs, env)
 	}
-	g.current = g.paths[envName]
-	return nil
+	return envs, nil
+}
+
+// RemoveEnv deletes the env name with everything in its directory.
+// A GOPATH outside of it, given to NewEnv, is left alone.
+func (g *GOROOT) RemoveEnv(name string) (*GOPATH, error) {
+	env, err := g.GetEnv(name)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(g.root(), name)
+	if err := removeAll(dir); err != nil {
+		return nil, errors.Wrapf(err, "failed to remove %s", dir)
+	}
+	if g.current != nil && g.current.EnvName == name {
+		g.current = nil
+	}
+	return env, nil
 }
 
+// GoPath is a GOPATH with the GOROOT it's used with
+//
+// Deprecated: use GOPATH.
 type GoPath struct {
 	Dir    string
 	BinDir string
 	Root   GoRoot
 }
 
+// GoRoot is a go installation
+//
+// Deprecated: see InstalledVersions for the installed toolchains.
 type GoRoot struct {
 	Dir    string
 	BinDir string
 }
 
+// removeAll removes dir like os.RemoveAll, making its directories writable first,
+// the module cache in a GOPATH is read-only
+func removeAll(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if err := os.Chmod(path, 0755); err != nil {
+			log.Debug().Err(err).Str("Path", path).Msg("failed to make writable")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
 func WinPathSetter(path string) string {
 	return fmt.Sprintf("if (-NOT $env:PATH.Split(';').Contains('%s')) "+
 		"{ "+
@@ -169,12 +362,17 @@ func WinPathUnsetter(path string) string {
 		",[EnvironmentVariableTarget]::User)", path)
 }
 
+// UnixPathSetter returns a POSIX shell line putting path in front of PATH unless it's already in it
 func UnixPathSetter(path string) string {
-	return fmt.Sprintf("if [[ ! $PATH =~ (^|:)%s(:|$) ]]; then export PATH=$PATH:%s; fi", path, path)
+	q := posixQuote(path)
+	return fmt.Sprintf(`case ":$PATH:" in *:%s:*) ;; *) PATH=%s${PATH:+:$PATH}; export PATH ;; esac`, q, q)
 }
 
+// UnixPathUnsetter returns a POSIX shell line removing path from PATH
 func UnixPathUnsetter(path string) string {
-	return fmt.Sprintf("export PATH=${PATH//:%s/}", path)
+	return fmt.Sprintf(`_gom_path=; _gom_ifs=$IFS; IFS=:; set -f; for _gom_dir in $PATH; do `+
+		`[ "$_gom_dir" = %s ] || _gom_path=${_gom_path:+$_gom_path:}$_gom_dir; done; `+
+		`set +f; IFS=$_gom_ifs; PATH=$_gom_path; export PATH; unset _gom_path _gom_ifs _gom_dir`, posixQuote(path))
 }
 
 var EnvsDir = func() string {
@@ -187,9 +385,57 @@ var EnvsDir = func() string {
 
 var AliasDir = filepath.Join(EnvsDir, "bin")
 
+// CurrentLink returns the path of the symlink pointing at the active toolchain in dataDir
+func CurrentLink(dataDir string) string {
+	return filepath.Join(dataDir, "current")
+}
+
+// CurrentTarget returns the toolchain the current link in dataDir points at, or an empty string if there's none
+func CurrentTarget(dataDir string) string {
+	target, err := os.Readlink(CurrentLink(dataDir))
+	if err != nil {
+		return ""
+	}
+	return target
+}
+
+// SwitchCurrent points link at target, replacing any previous link atomically by renaming a temporary link over it.
+// It returns the previous target of the link, or an empty string if there was none.
+func SwitchCurrent(link string, target string) (string, error) {
+	previous, err := os.Readlink(link)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", errors.Wrapf(err, "%s exists and is not a symlink", link)
+	}
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to create %s directory", filepath.Dir(link))
+	}
+	tmp := fmt.Sprintf("%s.tmp-%d", link, os.Getpid())
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if err := os.Symlink(target, tmp); err != nil {
+		return "", errors.Wrapf(err, "failed to link %s", target)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		if err := os.Remove(tmp); err != nil {
+			log.Error().Err(err).Msgf("failed to remove %s", tmp)
+		}
+		return "", errors.Wrapf(err, "failed to switch %s to %s", link, target)
+	}
+	log.Debug().Str("From", previous).Str("To", target).Msgf("switched %s", link)
+	return previous, nil
+}
+
+// SwitchEnv links the programs in the GOBIN of the env envName into AliasDir
+//
+// Deprecated: activate the env with the env activate command, env and shell-init put its GOBIN on PATH.
+//
 //goland:noinspection ALL
 func SwitchEnv(envName string) error {
 	binDir := filepath.Join(EnvsDir, envName, "bin")
+	if env, err := (&GOROOT{}).GetEnv(envName); err == nil {
+		binDir = env.BinDir
+	}
 	bins, err := os.ReadDir(binDir)
 	if err != nil {
 		return err
@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+)
+
+func TestCreateEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		existing []string
+		wantErr  bool
+		wantIs   error
+	}{
+		{name: "new", env: "work"},
+		{name: "dots and dashes", env: "go1.21-legacy_x"},
+		{name: "exists", env: "work", existing: []string{"work"}, wantErr: true, wantIs: ErrEnvExists},
+		{name: "path", env: "../work", wantErr: true},
+		{name: "hidden", env: ".work", wantErr: true},
+		{name: "empty", env: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGOROOT(t.TempDir())
+			for _, name := range tt.existing {
+				if _, err := g.CreateEnv(name, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			env, err := g.CreateEnv(tt.env, "go1.21.5")
+			if tt.wantErr {
+				if err == nil || tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+					t.Fatalf("CreateEnv() error = %v, want %v", err, tt.wantIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := filepath.Join(g.Dir, tt.env)
+			if env.Dir != dir || env.BinDir != filepath.Join(dir, "bin") || env.Go != "go1.21.5" {
+				t.Errorf("CreateEnv() = %+v", env)
+			}
+			got, err := g.GetEnv(tt.env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, env) {
+				t.Errorf("GetEnv() = %+v, want %+v", got, env)
+			}
+			b, err := os.ReadFile(filepath.Join(dir, EnvManifest))
+			if err != nil || strings.Contains(string(b), "Dir =") {
+				t.Errorf("manifest %q, %v", b, err)
+			}
+		})
+	}
+}
+
+func TestEnvs(t *testing.T) {
+	g := NewGOROOT(t.TempDir())
+	for _, name := range []string{"b", "a"} {
+		if _, err := g.CreateEnv(name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(g.Dir, "not-an-env"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	envs, err := g.Envs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, env := range envs {
+		names = append(names, env.EnvName)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("Envs() = %v, want [a b]", names)
+	}
+	b, err := json.Marshal(envs[0])
+	if err != nil || strings.Contains(string(b), "Dir") {
+		t.Errorf("json.Marshal() = %s, %v", b, err)
+	}
+}
+
+func TestRemoveEnv(t *testing.T) {
+	g := NewGOROOT(t.TempDir())
+	env, err := g.CreateEnv("work", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the module cache is read-only
+	cache := filepath.Join(env.Dir, "pkg", "mod", "example.com", "x@v1.0.0")
+	writeFiles(t, cache, map[string]string{"go.mod": "module example.com/x\n"})
+	if err := os.Chmod(cache, 0555); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.RemoveEnv("work"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(env.Dir); !os.IsNotExist(err) {
+		t.Errorf("%s is still there: %v", env.Dir, err)
+	}
+	if _, err := g.RemoveEnv("work"); !errors.Is(err, ErrEnvNotFound) {
+		t.Errorf("RemoveEnv() of a removed env error = %v, want ErrEnvNotFound", err)
+	}
+}
+
+func TestDeprecatedEnvAPI(t *testing.T) {
+	g := NewGOROOT(t.TempDir())
+	gopath := filepath.Join(t.TempDir(), "gopath")
+	if err := g.NewEnv("legacy", gopath); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.NewEnv("legacy", gopath); !errors.Is(err, ErrEnvExists) {
+		t.Errorf("NewEnv() of an existing env error = %v, want ErrEnvExists", err)
+	}
+	if err := g.SetCurrent("missing"); !errors.Is(err, ErrEnvNotFound) {
+		t.Errorf("SetCurrent() of a missing env error = %v, want ErrEnvNotFound", err)
+	}
+	if err := g.SetCurrent("legacy"); err != nil {
+		t.Fatal(err)
+	}
+	current := g.GetCurrent()
+	if current == nil || current.Dir != gopath || current.BinDir != filepath.Join(gopath, "bin") {
+		t.Fatalf("GetCurrent() = %+v, want the env in %s", current, gopath)
+	}
+	if _, err := g.RemoveEnv("legacy"); err != nil {
+		t.Fatal(err)
+	}
+	if g.GetCurrent() != nil {
+		t.Error("GetCurrent() returns a removed env")
+	}
+	if _, err := os.Stat(filepath.Join(gopath, "bin")); err != nil {
+		t.Errorf("RemoveEnv() removed the GOPATH given to NewEnv: %v", err)
+	}
+}
+
+func TestSwitchCurrent(t *testing.T) {
+	dir := t.TempDir()
+	link := CurrentLink(dir)
+	first := filepath.Join(dir, "go1.20")
+	second := filepath.Join(dir, "go1.21")
+	for _, d := range []string{first, second} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous, err := SwitchCurrent(link, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "" {
+		t.Errorf("previous = %q, want empty", previous)
+	}
+	previous, err = SwitchCurrent(link, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != first {
+		t.Errorf("previous = %q, want %q", previous, first)
+	}
+	if got := CurrentTarget(dir); got != second {
+		t.Errorf("CurrentTarget() = %q, want %q", got, second)
+	}
+	// the link is replaced by a rename, so no temporary link is left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp") {
+			t.Errorf("leftover temporary link %s", e.Name())
+		}
+	}
+	if got := CurrentTarget(t.TempDir()); got != "" {
+		t.Errorf("CurrentTarget() without a link = %q, want empty", got)
+	}
+}
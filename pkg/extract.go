@@ -0,0 +1,313 @@
+package pkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// archivePrefix is the top-level directory every Go release archive is packed under
+const archivePrefix = "go/"
+
+var (
+	ErrUnknownArchive = errors.New("unknown archive format")
+	ErrInstalled      = errors.New("toolchain already installed")
+)
+
+// ExtractArchive extracts the Go release archive at archivePath into dest, stripping the top-level go/ directory.
+// The archive is unpacked into a temporary directory next to dest which is renamed into place once complete,
+// so a partially extracted toolchain is never visible at dest.
+func ExtractArchive(archivePath string, dest string) error {
+	switch {
+	case strings.HasSuffix(archivePath, ".tar.gz"), strings.HasSuffix(archivePath, ".tgz"):
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		defer func(f *os.File) {
+			err := f.Close()
+			if err != nil {
+				log.Error().Err(err).Msgf("failed to close %s", archivePath)
+			}
+		}(f)
+		return extractAtomic(dest, func(dir string) error {
+			return extractTarGz(f, dir)
+		})
+	case strings.HasSuffix(archivePath, ".zip"):
+		return extractAtomic(dest, func(dir string) error {
+			return extractZip(archivePath, dir)
+		})
+	default:
+		return errors.Wrap(ErrUnknownArchive, archivePath)
+	}
+}
+
+// extractAtomic runs extract against a temporary directory and renames it to dest on success
+func extractAtomic(dest string, extract func(dir string) error) error {
+	if _, err := os.Lstat(dest); err == nil {
+		return errors.Wrap(ErrInstalled, dest)
+	}
+	parent := filepath.Dir(dest)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s directory", parent)
+	}
+	tmp, err := os.MkdirTemp(parent, "."+filepath.Base(dest)+".tmp-")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temp dir in %s", parent)
+	}
+	if err := extract(tmp); err != nil {
+		removeTemp(tmp)
+		return err
+	}
+	// MkdirTemp creates the directory with 0700
+	if err := os.Chmod(tmp, 0755); err != nil {
+		removeTemp(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		removeTemp(tmp)
+		return errors.Wrapf(err, "failed to move %s to %s", tmp, dest)
+	}
+	log.Debug().Str("Path", dest).Msg("extracted toolchain")
+	return nil
+}
+
+func removeTemp(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		log.Error().Err(err).Msgf("failed to remove the temp dir %s", dir)
+	}
+}
+
+// archivePath maps an archive entry name to a path under root, stripping the go/ prefix.
+// It returns an empty string for the prefix directory itself and an error for entries escaping root.
+func archivePath(root string, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if !strings.HasPrefix(name, archivePrefix) {
+		if name+"/" == archivePrefix {
+			return "", nil
+		}
+		return "", errors.Errorf("unexpected archive entry %q outside of %s", name, archivePrefix)
+	}
+	rel := path.Clean(strings.TrimPrefix(name, archivePrefix))
+	if rel == "." {
+		return "", nil
+	}
+	if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", errors.Errorf("illegal path traversal in archive entry %q", name)
+	}
+	return filepath.Join(root, filepath.FromSlash(rel)), nil
+}
+
+// checkLink makes sure a symlink at target pointing to linkname stays inside root
+func checkLink(root string, target string, linkname string) error {
+	if filepath.IsAbs(linkname) || path.IsAbs(linkname) {
+		return errors.Errorf("illegal absolute symlink %s -> %s", target, linkname)
+	}
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errors.Errorf("illegal symlink %s -> %s escapes %s", target, linkname, root)
+	}
+	return nil
+}
+
+// checkParents makes sure no directory between root and target is a symlink, so entries are never written
+// through a link created by an earlier entry of the archive. Missing directories are fine, they're created as real ones.
+func checkParents(root string, target string) error {
+	rel, err := filepath.Rel(root, filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	dir := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return errors.Errorf("illegal archive entry %s written through the symlink %s", target, dir)
+		}
+	}
+	return nil
+}
+
+// makeParent creates the directory of target after checking it isn't reached through a symlink
+func makeParent(root string, target string) error {
+	if err := checkParents(root, target); err != nil {
+		return err
+	}
+	return os.MkdirAll(filepath.Dir(target), 0755)
+}
+
+func writeFile(root string, target string, r io.Reader, mode fs.FileMode) error {
+	if err := makeParent(root, target); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "failed to write %s", target)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// Respect the archive mode regardless of umask
+	return os.Chmod(target, mode.Perm())
+}
+
+func writeLink(root string, target string, linkname string) error {
+	if err := checkLink(root, target, linkname); err != nil {
+		return err
+	}
+	if err := makeParent(root, target); err != nil {
+		return err
+	}
+	return os.Symlink(linkname, target)
+}
+
+func writeDir(root string, target string, mode fs.FileMode) error {
+	if err := checkParents(root, target); err != nil {
+		return err
+	}
+	if fi, err := os.Lstat(target); err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+		return errors.Errorf("illegal archive entry %s replacing a symlink", target)
+	}
+	return os.MkdirAll(target, mode.Perm()|0700)
+}
+
+func writeHardLink(root string, target string, src string) error {
+	if err := checkParents(root, src); err != nil {
+		return err
+	}
+	if err := makeParent(root, target); err != nil {
+		return err
+	}
+	return os.Link(src, target)
+}
+
+func extractTarGz(r io.Reader, root string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.Wrap(err, "failed to open gzip stream")
+	}
+	defer func(gz *gzip.Reader) {
+		err := gz.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to close gzip stream")
+		}
+	}(gz)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read tar entry")
+		}
+		target, err := archivePath(root, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := writeDir(root, target, fs.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(root, target, tr, fs.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeLink(root, target, hdr.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			src, err := archivePath(root, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := writeHardLink(root, target, src); err != nil {
+				return err
+			}
+		default:
+			log.Debug().Str("Entry", hdr.Name).Msgf("skipping unsupported tar entry type %c", hdr.Typeflag)
+		}
+	}
+}
+
+func extractZip(archive string, root string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", archive)
+	}
+	defer func(zr *zip.ReadCloser) {
+		err := zr.Close()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to close %s", archive)
+		}
+	}(zr)
+	for _, f := range zr.File {
+		target, err := archivePath(root, f.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		if err := extractZipEntry(f, root, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(f *zip.File, root string, target string) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return writeDir(root, target, mode)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", f.Name)
+	}
+	defer func(rc io.ReadCloser) {
+		err := rc.Close()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to close %s", f.Name)
+		}
+	}(rc)
+	if mode&fs.ModeSymlink != 0 {
+		linkname, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		return writeLink(root, target, string(linkname))
+	}
+	if mode.Perm() == 0 {
+		mode |= 0644
+	}
+	return writeFile(root, target, rc, mode)
+}
@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	body     string
+}
+
+func makeTarGz(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0644, Size: int64(len(e.body))}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractTarGz(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+	}{
+		{
+			name: "release",
+			entries: []tarEntry{
+				{name: "go/", typeflag: tar.TypeDir},
+				{name: "go/VERSION", typeflag: tar.TypeReg, body: "go1.21.5\n"},
+				{name: "go/bin/go", typeflag: tar.TypeReg, body: "binary"},
+				{name: "go/lib/link", typeflag: tar.TypeSymlink, linkname: "../VERSION"},
+				{name: "go/lib/hard", typeflag: tar.TypeLink, linkname: "go/VERSION"},
+			},
+		},
+		{
+			name: "traversal",
+			entries: []tarEntry{
+				{name: "go/../x", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "absolute symlink",
+			entries: []tarEntry{
+				{name: "go/etc", typeflag: tar.TypeSymlink, linkname: "/etc"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink escaping as text",
+			entries: []tarEntry{
+				{name: "go/d", typeflag: tar.TypeSymlink, linkname: "."},
+				{name: "go/d/e", typeflag: tar.TypeSymlink, linkname: "../.."},
+				{name: "go/d/e/x", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink chain",
+			entries: []tarEntry{
+				{name: "go/d", typeflag: tar.TypeSymlink, linkname: "."},
+				{name: "go/d/e", typeflag: tar.TypeSymlink, linkname: ".."},
+				{name: "go/d/e/x", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "file through symlinked dir",
+			entries: []tarEntry{
+				{name: "go/sub/", typeflag: tar.TypeDir},
+				{name: "go/d", typeflag: tar.TypeSymlink, linkname: "sub"},
+				{name: "go/d/x", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hard link through symlinked dir",
+			entries: []tarEntry{
+				{name: "go/d", typeflag: tar.TypeSymlink, linkname: "."},
+				{name: "go/x", typeflag: tar.TypeLink, linkname: "go/d/y"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			root := filepath.Join(parent, "go1.21.5")
+			if err := os.Mkdir(root, 0755); err != nil {
+				t.Fatal(err)
+			}
+			err := extractTarGz(bytes.NewReader(makeTarGz(t, tt.entries)), root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTarGz() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			entries, err := os.ReadDir(parent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("extractTarGz() wrote outside of the root: %v", entries)
+			}
+			if tt.wantErr {
+				return
+			}
+			b, err := os.ReadFile(filepath.Join(root, "lib", "link"))
+			if err != nil || string(b) != "go1.21.5\n" {
+				t.Errorf("symlink reads %q, %v", b, err)
+			}
+			b, err = os.ReadFile(filepath.Join(root, "lib", "hard"))
+			if err != nil || string(b) != "go1.21.5\n" {
+				t.Errorf("hard link reads %q, %v", b, err)
+			}
+		})
+	}
+}
@@ -3,16 +3,19 @@ package pkg
 import (
 	"context"
 	"fmt"
-	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/x0f5c3/zerolog/log"
+	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/goccy/go-json"
-	"github.com/valyala/fasthttp"
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/go-manager/pkg/progress"
 	"github.com/x0f5c3/go-manager/pkg/semver"
 	"github.com/x0f5c3/manic-go/pkg/downloader"
 )
@@ -23,8 +26,24 @@ const (
 	oS    = runtime.GOOS
 )
 
+// DefaultMirror is the base URL release archives are downloaded from
+const DefaultMirror = "https://go.dev/dl/"
+
+// feedQuery is appended to a mirror to get its version index
+const feedQuery = "?mode=json&include=all"
+
 type DownloadSettings struct {
 	OutDir string
+	// Mirror is the base URL to download archives from instead of DefaultMirror
+	Mirror string
+	// Client is used for downloads if set, see NewClient
+	Client *downloader.Client
+	// Cache is checked before downloading and filled with verified archives if set
+	Cache *DownloadCache
+	// Progress receives the progress of downloads and extraction, progress.Default() is used if it's nil
+	Progress progress.Reporter
+	// Stream installs extract archives while they're downloaded instead of saving them first, see File.StreamExtractContext
+	Stream bool
 	OSTriple
 }
 
@@ -48,8 +67,21 @@ func NewDownloadSettings(outDir string, osTriple ...OSTriple) *DownloadSettings
 	return &DownloadSettings{OutDir: outDir, OSTriple: CurrentKind}
 }
 
+// settingsOptions returns the options to fetch versions with, matching the download settings
+func settingsOptions(settings ...*DownloadSettings) []Option {
+	if len(settings) > 0 {
+		return []Option{WithMirror(settings[0].Mirror), WithClient(settings[0].Client)}
+	}
+	return nil
+}
+
 func DownloadLatest(outdir ...*DownloadSettings) error {
-	versions, err := GetVersions()
+	return DownloadLatestContext(context.Background(), outdir...)
+}
+
+// DownloadLatestContext is DownloadLatest, stopping with a CancelledError once ctx is done
+func DownloadLatestContext(ctx context.Context, outdir ...*DownloadSettings) error {
+	versions, err := GetVersionsContext(ctx, settingsOptions(outdir...)...)
 	if err != nil {
 		return err
 	}
@@ -57,26 +89,30 @@ func DownloadLatest(outdir ...*DownloadSettings) error {
 	if f == nil {
 		return fmt.Errorf("failed to find file for %s", versions[0].Version)
 	}
-	return f.Download(outdir...)
+	return f.DownloadContext(ctx, outdir...)
+}
+
+// GetVersions fetches the list of go releases, newest first.
+// Only stable releases are returned unless WithUnstable is passed.
+func GetVersions(opts ...Option) (Versions, error) {
+	return GetVersionsContext(context.Background(), opts...)
 }
 
-func GetVersions() (Versions, error) {
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseResponse(resp)
-	req := fasthttp.AcquireRequest()
-	defer fasthttp.ReleaseRequest(req)
-	req.SetRequestURI(dLURL)
-	req.Header.SetMethod("GET")
-	req.Header.Set("User-Agent", "manic-go")
-	err := fasthttp.Do(req, resp)
+// GetVersionsContext is GetVersions, stopping with a CancelledError once ctx is done
+func GetVersionsContext(ctx context.Context, opts ...Option) (Versions, error) {
+	o := newOptions(opts...)
+	body, err := loadIndex(ctx, o)
 	if err != nil {
 		return nil, err
 	}
 	var versions Versions
-	if err := json.Unmarshal(resp.Body(), &versions); err != nil {
+	if err := json.Unmarshal(body, &versions); err != nil {
 		return nil, err
 	}
-	versions = versions.OnlyStable()
+	if !o.unstable {
+		versions = versions.OnlyStable()
+	}
+	sort.Sort(&versions)
 	return versions, nil
 }
 
@@ -90,40 +126,95 @@ type File struct {
 }
 
 func (f *File) URL() string {
-	return "https://go.dev/dl/" + f.Filename
+	return DefaultMirror + f.Filename
+}
+
+// MirrorURL returns the URL of the file on the given mirror, or on DefaultMirror if it's empty
+func (f *File) MirrorURL(mirror string) string {
+	if mirror == "" {
+		return f.URL()
+	}
+	return strings.TrimSuffix(mirror, "/") + "/" + f.Filename
 }
 
 func (f *File) Download(outDir ...*DownloadSettings) error {
-	dl, err := downloader.New(f.URL(), f.Sha256, nil, &f.Size)
+	return f.DownloadContext(context.Background(), outDir...)
+}
+
+// DownloadContext downloads the file to its OutPath and verifies its checksum.
+// The file is written next to it with a .part suffix and only renamed into place once verified.
+// An interrupted download, including one stopped with a CancelledError once ctx is done, leaves the .part file
+// behind with a sidecar of the completed ranges, and the next call resumes it with range requests.
+// With a Cache in the settings a cached copy is used instead of downloading, and new downloads are added to it.
+func (f *File) DownloadContext(ctx context.Context, outDir ...*DownloadSettings) error {
+	url := f.URL()
+	client := defaultClient
+	var cache *DownloadCache
+	var reporter progress.Reporter
+	if len(outDir) > 0 {
+		url = f.MirrorURL(outDir[0].Mirror)
+		if outDir[0].Client != nil {
+			client = outDir[0].Client
+		}
+		cache = outDir[0].Cache
+		reporter = outDir[0].Progress
+	}
+	out := f.OutPath(outDir...)
+	if cache != nil {
+		if err := os.Remove(out); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		hit, err := cache.Get(f.Sha256, out)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to use the download cache")
+		}
+		if hit {
+			return nil
+		}
+	}
+	part, state := out+partSuffix, out+partStateSuffix
+	resumed, err := downloadFile(ctx, client, progress.Or(reporter), url, part, state, f.Sha256, int64(f.Size))
 	if err != nil {
+		if f.Size > 0 {
+			log.Warn().Err(err).Msgf("download of %s interrupted, run again to resume it", f.Filename)
+		} else {
+			removePartial(part, state)
+		}
 		return err
 	}
-	dled, err := dl.Download(10, 10, true)
-	if err != nil {
+	if err := verifySha256(part, f.Sha256); err != nil {
+		removePartial(part, state)
+		if resumed > 0 && errors.Is(err, ErrChecksumMismatch) {
+			log.Warn().Err(err).Msg("resumed download is corrupt, starting over")
+			return f.DownloadContext(ctx, outDir...)
+		}
+		return err
+	}
+	removePartial(state)
+	if err := os.Rename(part, out); err != nil {
 		return err
 	}
-	outPath := func() string {
-		if len(outDir) > 0 {
-			return filepath.Join(outDir[0].OutDir, f.Filename)
+	if cache != nil {
+		if err := cache.Put(out, f.Sha256); err != nil {
+			log.Warn().Err(err).Msg("failed to add the archive to the download cache")
 		}
-		return f.Filename
-	}()
-	return dled.Save(outPath)
+	}
+	return nil
+}
+
+// OutPath returns the path the file is saved to by Download
+func (f *File) OutPath(outDir ...*DownloadSettings) string {
+	if len(outDir) > 0 {
+		return filepath.Join(outDir[0].OutDir, f.Filename)
+	}
+	return f.Filename
 }
 
 type Versions []*GoVersion
 
 func (v *Versions) Parse() (*Versions, error) {
-	pb, err := pterm.DefaultProgressbar.WithTitle("Parsing versions").WithTotal(len(*v)).Start()
-	if err != nil {
-		return nil, err
-	}
-	defer func(pb *pterm.ProgressbarPrinter) {
-		_, err := pb.Stop()
-		if err != nil {
-			log.Error().Err(err).Msg("failed to stop progress bar")
-		}
-	}(pb)
+	task := progress.Default().Start(progress.OpParse, "Parsing versions", int64(len(*v)))
+	defer task.Done("")
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	wg := &sync.WaitGroup{}
@@ -133,7 +224,7 @@ func (v *Versions) Parse() (*Versions, error) {
 		wg.Add(1)
 		go func(version *GoVersion) {
 			defer wg.Done()
-			defer pb.Increment()
+			defer task.Add(1)
 			parsed, err := version.Parse()
 			if err != nil {
 				cancel()
@@ -172,7 +263,7 @@ func (v *Versions) Len() int {
 }
 
 func (v *Versions) Less(i, j int) bool {
-	cmp := semver.Compare((*v)[j].Version, (*v)[i].Version)
+	cmp := semver.CompareGo((*v)[j].Version, (*v)[i].Version)
 	if cmp != 0 {
 		return cmp < 0
 	}
@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestVersionsSort(t *testing.T) {
+	versions := goVersions("go1.21rc2", "go1.20", "go1.21.0", "go1.21beta1", "go1.9", "go1.21rc1", "go1.20.14")
+	sort.Sort(&versions)
+	want := []string{"go1.21.0", "go1.21rc2", "go1.21rc1", "go1.21beta1", "go1.20.14", "go1.20", "go1.9"}
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Version)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted versions = %v, want %v", got, want)
+	}
+}
+
+func TestOnlyStable(t *testing.T) {
+	versions := Versions{{Version: "go1.22rc1"}, {Version: "go1.21.5", Stable: true}, {Version: "go1.21beta1"}}
+	got := versions.OnlyStable()
+	if len(got) != 1 || got[0].Version != "go1.21.5" {
+		t.Errorf("OnlyStable() = %v, want only go1.21.5", got)
+	}
+}
+
+func TestGetVersionsUnstable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"version": "go1.21.5", "stable": true}, {"version": "go1.22rc1", "stable": false},
+			{"version": "go1.20.13", "stable": true}]`))
+	}))
+	t.Cleanup(srv.Close)
+	tests := []struct {
+		name     string
+		unstable bool
+		want     []string
+	}{
+		{"stable", false, []string{"go1.21.5", "go1.20.13"}},
+		{"unstable", true, []string{"go1.22rc1", "go1.21.5", "go1.20.13"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, err := GetVersionsContext(context.Background(), WithFeedURL(srv.URL), WithUnstable(tt.unstable))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range versions {
+				got = append(got, v.Version)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetVersionsContext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
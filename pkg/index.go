@@ -0,0 +1,167 @@
+package pkg
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+	"github.com/x0f5c3/manic-go/pkg/downloader"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+const (
+	indexFile     = "versions.json"
+	indexMetaFile = "versions.meta.json"
+)
+
+// ErrNoCache is returned in offline mode if there is no cached version index for the mirror or feed in use
+var ErrNoCache = errors.New("no cached version index, run once without offline mode to fill it")
+
+// indexMeta holds what's needed to revalidate the cached version index
+type indexMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+func (m *indexMeta) fresh(url string, ttl time.Duration) bool {
+	return m.URL == url && time.Since(m.Fetched) < ttl
+}
+
+type indexCache struct {
+	dir string
+}
+
+func (c indexCache) read() ([]byte, *indexMeta, error) {
+	body, err := os.ReadFile(filepath.Join(c.dir, indexFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(c.dir, indexMetaFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	var meta indexMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse the version index cache metadata")
+	}
+	return body, &meta, nil
+}
+
+func (c indexCache) writeMeta(meta *indexMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(c.dir, indexMetaFile), b, 0644)
+}
+
+func (c indexCache) write(body []byte, meta *indexMeta) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s directory", c.dir)
+	}
+	if err := writeFileAtomic(filepath.Join(c.dir, indexFile), body, 0644); err != nil {
+		return err
+	}
+	return c.writeMeta(meta)
+}
+
+// loadIndex returns the raw version index, from the cache if the options allow it
+func loadIndex(ctx context.Context, o *options) ([]byte, error) {
+	url := o.indexURL()
+	if o.cacheDir == "" {
+		if o.offline {
+			return nil, ErrNoCache
+		}
+		body, _, err := fetchIndex(ctx, o.client, url, nil)
+		return body, err
+	}
+	cache := indexCache{dir: o.cacheDir}
+	body, meta, err := cache.read()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Warn().Err(err).Msg("ignoring unreadable version index cache")
+	}
+	if o.offline {
+		if body == nil {
+			return nil, errors.Wrap(ErrNoCache, o.cacheDir)
+		}
+		if meta.URL != url {
+			return nil, errors.Wrapf(ErrNoCache, "%s holds the version index of %s, not %s", o.cacheDir, meta.URL, url)
+		}
+		log.Debug().Time("Fetched", meta.Fetched).Msg("offline, using cached version index")
+		return body, nil
+	}
+	if body != nil && meta.fresh(url, o.ttl) {
+		log.Debug().Time("Fetched", meta.Fetched).Msg("using cached version index")
+		return body, nil
+	}
+	if body == nil || meta.URL != url {
+		meta = nil
+	}
+	fetched, newMeta, err := fetchIndex(ctx, o.client, url, meta)
+	if err != nil {
+		if body != nil && !IsCancelled(err) {
+			log.Warn().Err(err).Msg("failed to refresh the version index, using the stale cache")
+			return body, nil
+		}
+		return nil, err
+	}
+	if fetched == nil {
+		log.Debug().Msg("version index not modified")
+		meta.Fetched = newMeta.Fetched
+		if err := cache.writeMeta(meta); err != nil {
+			log.Error().Err(err).Msg("failed to update the version index cache")
+		}
+		return body, nil
+	}
+	if err := cache.write(fetched, newMeta); err != nil {
+		log.Error().Err(err).Msg("failed to write the version index cache")
+	}
+	return fetched, nil
+}
+
+// fetchIndex downloads the version index with client, revalidating against meta if it's not nil.
+// It returns a nil body if the server reports the index as not modified.
+func fetchIndex(ctx context.Context, client *downloader.Client, url string, meta *indexMeta) ([]byte, *indexMeta, error) {
+	if client == nil {
+		client = defaultClient
+	}
+	req := fasthttp.AcquireRequest()
+	req.SetRequestURI(url)
+	req.Header.SetMethod("GET")
+	req.Header.Set("User-Agent", "manic-go")
+	if meta != nil {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	resp, err := doContext(ctx, client, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fasthttp.ReleaseResponse(resp)
+	newMeta := &indexMeta{
+		URL:          url,
+		ETag:         string(resp.Header.Peek("ETag")),
+		LastModified: string(resp.Header.Peek("Last-Modified")),
+		Fetched:      time.Now(),
+	}
+	switch resp.StatusCode() {
+	case fasthttp.StatusOK:
+		return append([]byte(nil), resp.Body()...), newMeta, nil
+	case fasthttp.StatusNotModified:
+		if meta != nil {
+			return nil, newMeta, nil
+		}
+	}
+	return nil, nil, errors.Errorf("failed to fetch %s: %s", url, fasthttp.StatusMessage(resp.StatusCode()))
+}
@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const testIndex = `[{"version": "go1.21.5", "stable": true}]`
+
+// indexServer serves testIndex with an ETag and a Last-Modified date and counts the requests by their response status
+type indexServer struct {
+	*httptest.Server
+	ok, notModified atomic.Int32
+	// fail makes every request fail with 500
+	fail atomic.Bool
+}
+
+func newIndexServer(t *testing.T) *indexServer {
+	s := &indexServer{}
+	modified := time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			s.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+			s.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.ok.Add(1)
+		_, _ = w.Write([]byte(testIndex))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func loadTestIndex(t *testing.T, opts ...Option) (string, error) {
+	t.Helper()
+	body, err := loadIndex(context.Background(), newOptions(opts...))
+	return string(body), err
+}
+
+func TestLoadIndexTTL(t *testing.T) {
+	srv := newIndexServer(t)
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		body, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(dir, time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body != testIndex {
+			t.Errorf("loadIndex() = %s, want %s", body, testIndex)
+		}
+	}
+	if got := srv.ok.Load() + srv.notModified.Load(); got != 1 {
+		t.Errorf("fresh cache made %d requests, want 1", got)
+	}
+}
+
+func TestLoadIndexRevalidate(t *testing.T) {
+	tests := []struct {
+		name string
+		// strip removes a validator from the cached metadata to revalidate with the other one
+		strip func(*indexMeta)
+	}{
+		{"etag", func(m *indexMeta) { m.LastModified = "" }},
+		{"last modified", func(m *indexMeta) { m.ETag = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newIndexServer(t)
+			dir := t.TempDir()
+			if _, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(dir, 0)); err != nil {
+				t.Fatal(err)
+			}
+			cache := indexCache{dir: dir}
+			_, meta, err := cache.read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.strip(meta)
+			fetched := meta.Fetched
+			if err := cache.writeMeta(meta); err != nil {
+				t.Fatal(err)
+			}
+			body, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(dir, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body != testIndex {
+				t.Errorf("loadIndex() = %s, want %s", body, testIndex)
+			}
+			if ok, notModified := srv.ok.Load(), srv.notModified.Load(); ok != 1 || notModified != 1 {
+				t.Errorf("got %d full and %d not modified responses, want 1 and 1", ok, notModified)
+			}
+			if _, meta, err = cache.read(); err != nil {
+				t.Fatal(err)
+			}
+			if !meta.Fetched.After(fetched) {
+				t.Errorf("revalidation didn't update the fetch time %s", meta.Fetched)
+			}
+		})
+	}
+}
+
+func TestLoadIndexOffline(t *testing.T) {
+	srv := newIndexServer(t)
+	dir := t.TempDir()
+	if _, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(dir, 0), WithOffline(true)); !errors.Is(err, ErrNoCache) {
+		t.Errorf("offline with an empty cache error = %v, want ErrNoCache", err)
+	}
+	if _, err := loadTestIndex(t, WithFeedURL(srv.URL), WithOffline(true)); !errors.Is(err, ErrNoCache) {
+		t.Errorf("offline without a cache error = %v, want ErrNoCache", err)
+	}
+	if _, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(dir, 0)); err != nil {
+		t.Fatal(err)
+	}
+	srv.fail.Store(true)
+	body, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(dir, 0), WithOffline(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != testIndex {
+		t.Errorf("loadIndex() = %s, want %s", body, testIndex)
+	}
+	if _, err := loadTestIndex(t, WithFeedURL(srv.URL+"/other"), WithCache(dir, 0), WithOffline(true)); !errors.Is(err, ErrNoCache) {
+		t.Errorf("offline with the cache of another feed error = %v, want ErrNoCache", err)
+	}
+	if got := srv.ok.Load() + srv.notModified.Load(); got != 1 {
+		t.Errorf("offline mode made %d requests, want only the one filling the cache", got-1)
+	}
+}
+
+func TestLoadIndexStale(t *testing.T) {
+	srv := newIndexServer(t)
+	dir := t.TempDir()
+	if _, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(dir, 0)); err != nil {
+		t.Fatal(err)
+	}
+	srv.fail.Store(true)
+	body, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(dir, 0))
+	if err != nil {
+		t.Fatalf("failed refresh with a stale cache error = %v, want the stale cache", err)
+	}
+	if body != testIndex {
+		t.Errorf("loadIndex() = %s, want %s", body, testIndex)
+	}
+	if _, err := loadTestIndex(t, WithFeedURL(srv.URL), WithCache(t.TempDir(), 0)); err == nil {
+		t.Error("failed fetch without a cache succeeded")
+	}
+}
@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/go-manager/pkg/progress"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// InstallDir returns the directory the toolchain for the given go version is installed to
+func InstallDir(envsDir string, version string) string {
+	return filepath.Join(envsDir, version)
+}
+
+// InstallLatest downloads the latest stable release and installs it under envsDir
+func InstallLatest(envsDir string, settings ...*DownloadSettings) (string, error) {
+	versions, err := GetVersions(settingsOptions(settings...)...)
+	if err != nil {
+		return "", err
+	}
+	return versions[0].Install(envsDir, settings...)
+}
+
+// Install downloads the archive for this version and extracts it into a versioned directory under envsDir.
+// The archive is saved to the OutDir of the settings, or next to envsDir if none is set, and removed once extracted.
+// With Stream set in the settings a tar.gz archive that isn't cached is extracted while it's downloaded instead.
+// It returns the directory the toolchain was installed to.
+func (v *GoVersion) Install(envsDir string, settings ...*DownloadSettings) (string, error) {
+	return v.InstallContext(context.Background(), envsDir, settings...)
+}
+
+// InstallContext is Install, stopping with a CancelledError once ctx is done.
+// Nothing is left behind in envsDir when the download is cancelled.
+func (v *GoVersion) InstallContext(ctx context.Context, envsDir string, settings ...*DownloadSettings) (string, error) {
+	f := v.File(settings...)
+	if f == nil {
+		return "", fmt.Errorf("failed to find file for %s", v.Version)
+	}
+	if f.Kind != iND {
+		return "", fmt.Errorf("%s is a %s, only archives can be installed", f.Filename, f.Kind)
+	}
+	dest := InstallDir(envsDir, v.Version)
+	if _, err := os.Lstat(dest); err == nil {
+		return dest, errors.Wrap(ErrInstalled, v.Version)
+	}
+	dlSettings := NewDownloadSettings(filepath.Dir(envsDir))
+	if len(settings) > 0 {
+		copied := *settings[0]
+		dlSettings = &copied
+		if dlSettings.OutDir == "" {
+			dlSettings.OutDir = filepath.Dir(envsDir)
+		}
+	}
+	if dlSettings.Stream && f.CanStream() && (dlSettings.Cache == nil || !dlSettings.Cache.Has(f.Sha256)) {
+		if err := f.StreamExtractContext(ctx, dest, dlSettings); err != nil {
+			return "", errors.Wrapf(err, "failed to install %s", f.Filename)
+		}
+		log.Debug().Str("Version", v.Version).Str("Path", dest).Msg("installed toolchain")
+		return dest, nil
+	}
+	if err := os.MkdirAll(dlSettings.OutDir, 0755); err != nil {
+		return "", errors.Wrapf(err, "failed to create %s directory", dlSettings.OutDir)
+	}
+	if err := f.DownloadContext(ctx, dlSettings); err != nil {
+		return "", errors.Wrapf(err, "failed to download %s", f.Filename)
+	}
+	archive := f.OutPath(dlSettings)
+	task := progress.Or(dlSettings.Progress).Start(progress.OpExtract, "Extracting "+f.Filename, 0)
+	if err := ExtractArchive(archive, dest); err != nil {
+		err = errors.Wrapf(err, "failed to extract %s", archive)
+		task.Fail(err)
+		return "", err
+	}
+	task.Done("Extracted " + f.Filename)
+	if err := os.Remove(archive); err != nil {
+		log.Error().Err(err).Msgf("failed to remove %s", archive)
+	}
+	log.Debug().Str("Version", v.Version).Str("Path", dest).Msg("installed toolchain")
+	return dest, nil
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// versionFile is the file at the root of every toolchain holding its version
+const versionFile = "VERSION"
+
+// ReadToolchainVersion returns the go version of the toolchain installed in dir, read from its VERSION file
+func ReadToolchainVersion(dir string) (string, error) {
+	f, err := os.Open(filepath.Join(dir, versionFile))
+	if err != nil {
+		return "", err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to close %s", f.Name())
+		}
+	}(f)
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.Errorf("%s is empty", f.Name())
+	}
+	return strings.TrimSpace(sc.Text()), nil
+}
+
+// InstalledVersions scans envsDir for installed toolchains.
+// Only directories holding a VERSION file matching their name, as created by Install, are considered.
+func InstalledVersions(envsDir string) (Versions, error) {
+	entries, err := os.ReadDir(envsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", envsDir)
+	}
+	var versions Versions
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(envsDir, entry.Name())
+		version, err := ReadToolchainVersion(dir)
+		if err != nil {
+			log.Debug().Err(err).Str("Path", dir).Msg("not a toolchain, skipping")
+			continue
+		}
+		if version != entry.Name() {
+			log.Debug().Str("Path", dir).Str("Version", version).Msg("toolchain version doesn't match its directory, skipping")
+			continue
+		}
+		versions = append(versions, &GoVersion{
+			Version: version,
+			Stable:  !strings.Contains(version, "beta") && !strings.Contains(version, "rc"),
+		})
+	}
+	return versions, nil
+}
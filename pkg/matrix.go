@@ -0,0 +1,241 @@
+package pkg
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/pkg/errors"
+)
+
+// Outcomes of a matrix job
+const (
+	MatrixPass  = "pass"
+	MatrixFail  = "fail"
+	MatrixError = "error"
+	// MatrixCancelled is the outcome of a job that didn't finish because the matrix was interrupted
+	MatrixCancelled = "cancelled"
+)
+
+// MatrixJob runs the matrix command with one toolchain
+type MatrixJob struct {
+	Query   string
+	Version string
+	// Env is the environment of the command, set up for the toolchain
+	Env []string
+}
+
+// MatrixResult is the outcome of a MatrixJob
+type MatrixResult struct {
+	Query     string        `json:"query"`
+	Version   string        `json:"version"`
+	Status    string        `json:"status"`
+	ExitCode  int           `json:"exit_code"`
+	Duration  time.Duration `json:"-"`
+	Seconds   float64       `json:"seconds"`
+	StdoutLog string        `json:"stdout_log"`
+	StderrLog string        `json:"stderr_log"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Passed reports whether all results passed
+func Passed(results []MatrixResult) bool {
+	for _, r := range results {
+		if r.Status != MatrixPass {
+			return false
+		}
+	}
+	return true
+}
+
+// RunMatrix runs the program name with args once per job, at most parallel at a time.
+// The output of every run goes to <version>.stdout.log and <version>.stderr.log in logDir,
+// done is called as the runs finish. The results are in the order of the jobs.
+// Once ctx is done the running jobs are stopped and the results are returned with the error of ctx,
+// the jobs that didn't finish are MatrixCancelled.
+func RunMatrix(ctx context.Context, jobs []MatrixJob, name string, args []string, logDir string, parallel int, done func(MatrixResult)) ([]MatrixResult, error) {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", logDir)
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]MatrixResult, len(jobs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job MatrixJob) {
+			defer wg.Done()
+			var res MatrixResult
+			select {
+			case sem <- struct{}{}:
+				res = runMatrixJob(ctx, job, name, args, logDir)
+				<-sem
+			case <-ctx.Done():
+				res = cancelledJob(ctx, job, logDir)
+			}
+			results[i] = res
+			if done != nil {
+				mu.Lock()
+				done(res)
+				mu.Unlock()
+			}
+		}(i, job)
+	}
+	wg.Wait()
+	return results, ctx.Err()
+}
+
+func newMatrixResult(job MatrixJob, logDir string) MatrixResult {
+	return MatrixResult{
+		Query:     job.Query,
+		Version:   job.Version,
+		StdoutLog: filepath.Join(logDir, job.Version+".stdout.log"),
+		StderrLog: filepath.Join(logDir, job.Version+".stderr.log"),
+	}
+}
+
+// cancelledJob is the result of a job that was interrupted or never started
+func cancelledJob(ctx context.Context, job MatrixJob, logDir string) MatrixResult {
+	res := newMatrixResult(job, logDir)
+	res.Status, res.ExitCode, res.Error = MatrixCancelled, -1, ctx.Err().Error()
+	return res
+}
+
+func runMatrixJob(ctx context.Context, job MatrixJob, name string, args []string, logDir string) MatrixResult {
+	res := newMatrixResult(job, logDir)
+	fail := func(err error) MatrixResult {
+		res.Status, res.ExitCode, res.Error = MatrixError, -1, err.Error()
+		return res
+	}
+	if ctx.Err() != nil {
+		return cancelledJob(ctx, job, logDir)
+	}
+	path, err := lookPath(name, job.Env)
+	if err != nil {
+		return fail(err)
+	}
+	stdout, err := os.Create(res.StdoutLog)
+	if err != nil {
+		return fail(err)
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(res.StderrLog)
+	if err != nil {
+		return fail(err)
+	}
+	defer stderr.Close()
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.Env = job.Env
+	start := time.Now()
+	err = cmd.Run()
+	res.Duration = time.Since(start)
+	res.Seconds = res.Duration.Seconds()
+	var exit *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		// killed by the interrupt, whatever it exited with
+		res.Status, res.ExitCode, res.Error = MatrixCancelled, -1, ctx.Err().Error()
+	case errors.As(err, &exit):
+		res.Status, res.ExitCode = MatrixFail, exitCode(exit)
+	case err != nil:
+		return fail(errors.Wrapf(err, "failed to run %s", path))
+	default:
+		res.Status = MatrixPass
+	}
+	return res
+}
+
+// WriteMatrixJSON writes the results as a JSON array
+func WriteMatrixJSON(w io.Writer, results []MatrixResult) error {
+	b, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteMatrixJUnit writes the results as a JUnit XML suite called name with a test case per version,
+// cancelled versions are skipped. The output of the runs is read back from their log files.
+func WriteMatrixJUnit(w io.Writer, name string, results []MatrixResult) error {
+	suite := junitSuite{Name: name, Tests: len(results)}
+	var total time.Duration
+	for _, r := range results {
+		c := junitCase{Name: r.Version, ClassName: name, Time: junitSeconds(r.Duration)}
+		switch r.Status {
+		case MatrixFail:
+			suite.Failures++
+			c.Failure = &junitMessage{Message: fmt.Sprintf("exit code %d", r.ExitCode)}
+		case MatrixError:
+			suite.Errors++
+			c.Error = &junitMessage{Message: r.Error}
+		case MatrixCancelled:
+			suite.Skipped++
+			c.Skipped = &junitMessage{Message: r.Error}
+		}
+		if b, err := os.ReadFile(r.StdoutLog); err == nil {
+			c.SystemOut = string(b)
+		}
+		if b, err := os.ReadFile(r.StderrLog); err == nil {
+			c.SystemErr = string(b)
+		}
+		total += r.Duration
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Time = junitSeconds(total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
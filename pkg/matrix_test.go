@@ -0,0 +1,189 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunMatrixParallel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("runs shell scripts")
+	}
+	root := t.TempDir()
+	versions := []string{"go1.21.5", "go1.22.0", "go1.22.1"}
+	exits := map[string]string{"go1.21.5": "0", "go1.22.0": "0", "go1.22.1": "3"}
+	var jobs []MatrixJob
+	for _, v := range versions {
+		bin := filepath.Join(root, v, "bin")
+		// both runs look the program up while the other is running
+		writeScript(t, bin, "go", "sleep 0.2\necho "+v+" \"$@\"\nexit "+exits[v]+"\n", 0755)
+		jobs = append(jobs, MatrixJob{Query: v, Version: v, Env: SetEnv(os.Environ(), "PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))})
+	}
+	var done []string
+	results, err := RunMatrix(context.Background(), jobs, "go", []string{"version"}, filepath.Join(root, "logs"), 2, func(r MatrixResult) {
+		done = append(done, r.Version)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != len(jobs) {
+		t.Errorf("done called for %v", done)
+	}
+	for i, r := range results {
+		v := versions[i]
+		wantStatus, wantExit := MatrixPass, 0
+		if v == "go1.22.1" {
+			wantStatus, wantExit = MatrixFail, 3
+		}
+		if r.Version != v || r.Status != wantStatus || r.ExitCode != wantExit {
+			t.Errorf("results[%d] = %s %s %d, want %s %s %d", i, r.Version, r.Status, r.ExitCode, v, wantStatus, wantExit)
+		}
+		b, err := os.ReadFile(r.StdoutLog)
+		if err != nil || string(b) != v+" version\n" {
+			t.Errorf("%s ran the go of another job: %q, %v", v, b, err)
+		}
+	}
+	if Passed(results) {
+		t.Error("Passed() = true with a failed job")
+	}
+}
+
+func TestRunMatrixCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("runs shell scripts")
+	}
+	root := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scripts := map[string]string{"go1.21.5": "exit 0\n", "go1.22.0": "sleep 10\n", "go1.23.0": "exit 0\n"}
+	var jobs []MatrixJob
+	for _, v := range []string{"go1.21.5", "go1.22.0", "go1.23.0"} {
+		bin := filepath.Join(root, v, "bin")
+		writeScript(t, bin, "go", scripts[v], 0755)
+		jobs = append(jobs, MatrixJob{Query: v, Version: v, Env: SetEnv(os.Environ(), "PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))})
+	}
+	// the interrupt comes while go1.22.0 runs, the others run before it or are cancelled as well
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	results, err := RunMatrix(ctx, jobs, "go", nil, filepath.Join(root, "logs"), 1, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RunMatrix() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RunMatrix() took %s, the running job wasn't stopped", elapsed)
+	}
+	if len(results) != len(jobs) {
+		t.Fatalf("RunMatrix() returned %d results, want %d", len(results), len(jobs))
+	}
+	for i, r := range results {
+		if r.Version != jobs[i].Version {
+			t.Errorf("results[%d] is for %s, want %s", i, r.Version, jobs[i].Version)
+		}
+		want := []string{MatrixPass, MatrixCancelled}
+		if r.Version == "go1.22.0" {
+			want = want[1:]
+		}
+		if r.Status != want[0] && r.Status != want[len(want)-1] {
+			t.Errorf("%s = %s, want one of %v", r.Version, r.Status, want)
+		}
+	}
+}
+
+func TestWriteMatrixJUnit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go1.21.5.stdout.log": "ok\n",
+		"go1.21.5.stderr.log": "",
+		"go1.22.0.stdout.log": "",
+		"go1.22.0.stderr.log": "FAIL <x>\n",
+	})
+	logs := func(v string) (string, string) {
+		return filepath.Join(dir, v+".stdout.log"), filepath.Join(dir, v+".stderr.log")
+	}
+	pass := MatrixResult{Version: "go1.21.5", Status: MatrixPass, Duration: 1500 * time.Millisecond}
+	pass.StdoutLog, pass.StderrLog = logs("go1.21.5")
+	fail := MatrixResult{Version: "go1.22.0", Status: MatrixFail, ExitCode: 1, Duration: 250 * time.Millisecond}
+	fail.StdoutLog, fail.StderrLog = logs("go1.22.0")
+	broken := MatrixResult{Version: "go1.23.0", Status: MatrixError, ExitCode: -1, Error: `go not found in PATH`}
+	broken.StdoutLog, broken.StderrLog = logs("go1.23.0")
+	cancelled := MatrixResult{Version: "go1.24.0", Status: MatrixCancelled, ExitCode: -1, Error: "context canceled"}
+	cancelled.StdoutLog, cancelled.StderrLog = logs("go1.24.0")
+	tests := []struct {
+		name    string
+		results []MatrixResult
+		want    string
+	}{
+		{
+			name:    "passed",
+			results: []MatrixResult{pass},
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="matrix" tests="1" failures="0" errors="0" skipped="0" time="1.500">
+    <testcase name="go1.21.5" classname="matrix" time="1.500">
+      <system-out>ok&#xA;</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+		{
+			name:    "failed, errored and cancelled",
+			results: []MatrixResult{pass, fail, broken, cancelled},
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="matrix" tests="4" failures="1" errors="1" skipped="1" time="1.750">
+    <testcase name="go1.21.5" classname="matrix" time="1.500">
+      <system-out>ok&#xA;</system-out>
+    </testcase>
+    <testcase name="go1.22.0" classname="matrix" time="0.250">
+      <failure message="exit code 1"></failure>
+      <system-err>FAIL &lt;x&gt;&#xA;</system-err>
+    </testcase>
+    <testcase name="go1.23.0" classname="matrix" time="0.000">
+      <error message="go not found in PATH"></error>
+    </testcase>
+    <testcase name="go1.24.0" classname="matrix" time="0.000">
+      <skipped message="context canceled"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteMatrixJUnit(&buf, "matrix", tt.results); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteMatrixJUnit() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMatrixJSON(t *testing.T) {
+	var buf bytes.Buffer
+	results := []MatrixResult{{Query: "1.21", Version: "go1.21.5", Status: MatrixPass, Duration: time.Second, Seconds: 1,
+		StdoutLog: "go1.21.5.stdout.log", StderrLog: "go1.21.5.stderr.log"}}
+	if err := WriteMatrixJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"query": "1.21"`, `"status": "pass"`, `"seconds": 1`,
+		`"stdout_log": "go1.21.5.stdout.log"`, `"stderr_log": "go1.21.5.stderr.log"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteMatrixJSON() = %s, want it to contain %s", buf.String(), want)
+		}
+	}
+}
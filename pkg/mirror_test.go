@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const mirrorArchive = "go1.21.5.linux-amd64.tar.gz"
+
+// fakeMirror serves a version index listing a single archive with the checksum of want, and serves body as the archive
+type fakeMirror struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+func newFakeMirror(t *testing.T, want, body []byte) *fakeMirror {
+	t.Helper()
+	sum := sha256.Sum256(want)
+	index := fmt.Sprintf(`[{"version": "go1.21.5", "stable": true, "files": [
+		{"filename": %q, "os": "linux", "arch": "amd64", "sha256": %q, "size": %d, "kind": "archive"}]}]`,
+		mirrorArchive, hex.EncodeToString(sum[:]), len(want))
+	m := &fakeMirror{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.requests = append(m.requests, r.URL.RequestURI())
+		m.mu.Unlock()
+		switch r.URL.Path {
+		case "/", "/feed.json":
+			_, _ = w.Write([]byte(index))
+		case "/" + mirrorArchive:
+			http.ServeContent(w, r, mirrorArchive, time.Time{}, bytes.NewReader(body))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+// requested reports whether the mirror got a request for uri
+func (m *fakeMirror) requested(uri string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.requests {
+		if r == uri {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetVersionsMirror(t *testing.T) {
+	archive := []byte("archive")
+	tests := []struct {
+		name    string
+		opts    func(url string) []Option
+		wantURI string
+	}{
+		{"mirror", func(url string) []Option { return []Option{WithMirror(url)} }, "/" + feedQuery},
+		{"mirror with a slash", func(url string) []Option { return []Option{WithMirror(url + "/")} }, "/" + feedQuery},
+		{"feed url", func(url string) []Option { return []Option{WithFeedURL(url + "/feed.json")} }, "/feed.json"},
+		{"feed url wins", func(url string) []Option {
+			return []Option{WithMirror("http://127.0.0.1:1/"), WithFeedURL(url + "/feed.json")}
+		}, "/feed.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFakeMirror(t, archive, archive)
+			versions, err := GetVersionsContext(context.Background(), tt.opts(m.URL)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(versions) != 1 || versions[0].Version != "go1.21.5" {
+				t.Fatalf("GetVersionsContext() = %v, want go1.21.5", versions)
+			}
+			if !m.requested(tt.wantURI) {
+				t.Errorf("mirror got %v, want a request for %s", m.requests, tt.wantURI)
+			}
+		})
+	}
+}
+
+func TestDownloadMirror(t *testing.T) {
+	archive := []byte("the release archive")
+	tests := []struct {
+		name    string
+		body    []byte
+		wantErr error
+	}{
+		{"verified", archive, nil},
+		{"tampered", []byte("a tampered archive!"), ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFakeMirror(t, archive, tt.body)
+			versions, err := GetVersionsContext(context.Background(), WithMirror(m.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			settings := &DownloadSettings{OutDir: t.TempDir(), Mirror: m.URL, OSTriple: NewOSTriple("archive", "linux", "amd64")}
+			f := versions[0].File(settings)
+			if f == nil {
+				t.Fatal("no file for linux/amd64")
+			}
+			if got, want := f.MirrorURL(settings.Mirror), m.URL+"/"+mirrorArchive; got != want {
+				t.Errorf("MirrorURL() = %s, want %s", got, want)
+			}
+			err = f.DownloadContext(context.Background(), settings)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DownloadContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if !m.requested("/" + mirrorArchive) {
+				t.Errorf("mirror got %v, want a request for the archive", m.requests)
+			}
+			b, err := os.ReadFile(filepath.Join(settings.OutDir, mirrorArchive))
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Error("the tampered archive was kept")
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(b, archive) {
+				t.Errorf("downloaded %q, %v, want %q", b, err, archive)
+			}
+		})
+	}
+}
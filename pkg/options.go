@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"strings"
+	"time"
+
+	"github.com/x0f5c3/manic-go/pkg/downloader"
+)
+
+type options struct {
+	unstable bool
+	cacheDir string
+	ttl      time.Duration
+	offline  bool
+	mirror   string
+	feedURL  string
+	client   *downloader.Client
+}
+
+// Option configures how versions are fetched
+type Option func(*options)
+
+// WithUnstable keeps beta and release candidate versions in the list
+func WithUnstable(unstable bool) Option {
+	return func(o *options) {
+		o.unstable = unstable
+	}
+}
+
+// WithCache caches the version index in dir, it's only fetched again once it's older than ttl.
+// Stale entries are revalidated with the server, so a ttl of 0 always makes a conditional request.
+func WithCache(dir string, ttl time.Duration) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+		o.ttl = ttl
+	}
+}
+
+// WithOffline serves the version index only from the cache set with WithCache, without touching the network
+func WithOffline(offline bool) Option {
+	return func(o *options) {
+		o.offline = offline
+	}
+}
+
+// WithMirror fetches the version index from the given mirror of https://go.dev/dl/ unless a feed URL is set
+func WithMirror(mirror string) Option {
+	return func(o *options) {
+		o.mirror = mirror
+	}
+}
+
+// WithFeedURL fetches the version index from the given URL
+func WithFeedURL(url string) Option {
+	return func(o *options) {
+		o.feedURL = url
+	}
+}
+
+// WithClient fetches the version index with the given client, see NewClient
+func WithClient(client *downloader.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// indexURL returns the URL of the version index according to the mirror and feed URL options
+func (o *options) indexURL() string {
+	if o.feedURL != "" {
+		return o.feedURL
+	}
+	if o.mirror != "" {
+		return strings.TrimSuffix(o.mirror, "/") + "/" + feedQuery
+	}
+	return dLURL
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// Files pinning the go version of a project, checked in this order in every directory
+const (
+	GoVersionFile = ".go-version"
+	GomFile       = ".gom.toml"
+	GoWorkFile    = "go.work"
+	GoModFile     = "go.mod"
+)
+
+// Pin is a version query pinned by a project file
+type Pin struct {
+	// Query is the version query, see Versions.Resolve
+	Query string `json:"query"`
+	// File is the path of the file the pin was read from
+	File string `json:"file"`
+	// Directive is the go.mod or go.work line the pin was read from, empty for other files
+	Directive string `json:"directive,omitempty"`
+	// Minimum is set when the query is only the lowest version the project needs, like for the go line of go.mod,
+	// any version satisfying it does and the one in use otherwise is preferred, see SatisfiedBy
+	Minimum bool `json:"minimum,omitempty"`
+}
+
+// SatisfiedBy reports whether the exact go version, like go1.21.5, may be used instead of resolving the query of
+// a minimum pin. Pins that aren't a minimum are never satisfied by another version.
+func (p *Pin) SatisfiedBy(version string) bool {
+	if !p.Minimum || version == "" {
+		return false
+	}
+	_, err := (&Versions{{Version: version}}).Resolve(p.Query)
+	return err == nil
+}
+
+// gomFile is the layout of .gom.toml
+type gomFile struct {
+	Go string `toml:"go"`
+}
+
+// FindPin walks up from dir looking for a file pinning the go version and returns the first pin found, or nil if there's none.
+// In every directory .go-version wins over .gom.toml, which wins over go.work and go.mod.
+// The toolchain line of go.work and go.mod wins over their go line, the go line is a minimum version, see Pin.Minimum.
+func FindPin(dir string) (*Pin, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for _, read := range []func(string) (*Pin, error){readGoVersion, readGomFile, readGoDirectives(GoWorkFile), readGoDirectives(GoModFile)} {
+			pin, err := read(dir)
+			if err != nil {
+				return nil, err
+			}
+			if pin != nil {
+				log.Debug().Str("File", pin.File).Str("Query", pin.Query).Msg("found version pin")
+				return pin, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// readPinFile reads the file name in dir, nil is returned if it doesn't exist
+func readPinFile(dir, name string) ([]byte, string, error) {
+	path := filepath.Join(dir, name)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, path, nil
+	}
+	if err != nil {
+		return nil, path, errors.Wrapf(err, "failed to read %s", path)
+	}
+	return b, path, nil
+}
+
+// readGoVersion reads the first line of .go-version that isn't empty or a comment
+func readGoVersion(dir string) (*Pin, error) {
+	b, path, err := readPinFile(dir, GoVersionFile)
+	if b == nil || err != nil {
+		return nil, err
+	}
+	sc := bufio.NewScanner(strings.NewReader(string(b)))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return &Pin{Query: line, File: path}, nil
+	}
+	return nil, errors.Errorf("%s doesn't contain a version", path)
+}
+
+// readGomFile reads the go key of .gom.toml
+func readGomFile(dir string) (*Pin, error) {
+	b, path, err := readPinFile(dir, GomFile)
+	if b == nil || err != nil {
+		return nil, err
+	}
+	var f gomFile
+	if err := toml.Unmarshal(b, &f); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	if strings.TrimSpace(f.Go) == "" {
+		return nil, errors.Errorf("%s doesn't set go", path)
+	}
+	return &Pin{Query: strings.TrimSpace(f.Go), File: path}, nil
+}
+
+// readGoDirectives returns a reader of the toolchain or go line of the go.mod or go.work file name
+func readGoDirectives(name string) func(string) (*Pin, error) {
+	return func(dir string) (*Pin, error) {
+		b, path, err := readPinFile(dir, name)
+		if b == nil || err != nil {
+			return nil, err
+		}
+		var goLine, toolchain string
+		sc := bufio.NewScanner(strings.NewReader(string(b)))
+		for sc.Scan() {
+			line := sc.Text()
+			if i := strings.Index(line, "//"); i >= 0 {
+				line = line[:i]
+			}
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			switch fields[0] {
+			case "go":
+				goLine = fields[1]
+			case "toolchain":
+				toolchain = fields[1]
+			}
+		}
+		switch {
+		case toolchain != "" && toolchain != "default":
+			// the toolchain line names an exact release like go1.21.5, possibly with a suffix like go1.21.5+custom
+			return &Pin{Query: strings.SplitN(toolchain, "+", 2)[0], File: path, Directive: "toolchain"}, nil
+		case goLine != "":
+			return &Pin{Query: ">=" + goLine, File: path, Directive: "go", Minimum: true}, nil
+		}
+		// a go.mod without a go line pins nothing, keep looking further up
+		return nil, nil
+	}
+}
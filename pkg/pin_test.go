@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindPin(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		dir       string
+		want      *Pin
+		wantFile  string
+		wantError bool
+	}{
+		{
+			name:     "go-version",
+			files:    map[string]string{".go-version": "# pinned\n\n1.21.5\n"},
+			dir:      "a/b",
+			want:     &Pin{Query: "1.21.5"},
+			wantFile: ".go-version",
+		},
+		{
+			name:     "gom file",
+			files:    map[string]string{".gom.toml": "go = '1.20'\n"},
+			dir:      ".",
+			want:     &Pin{Query: "1.20"},
+			wantFile: ".gom.toml",
+		},
+		{
+			name:     "go-version wins",
+			files:    map[string]string{".go-version": "1.21\n", ".gom.toml": "go = '1.20'\n", "go.mod": "module x\n\ngo 1.19\n"},
+			dir:      ".",
+			want:     &Pin{Query: "1.21"},
+			wantFile: ".go-version",
+		},
+		{
+			name:     "go line is a minimum",
+			files:    map[string]string{"go.mod": "module x\n\ngo 1.21 // comment\n"},
+			dir:      "pkg",
+			want:     &Pin{Query: ">=1.21", Directive: "go", Minimum: true},
+			wantFile: "go.mod",
+		},
+		{
+			name:     "toolchain line is exact",
+			files:    map[string]string{"go.mod": "module x\n\ngo 1.21\n\ntoolchain go1.22.3+custom\n"},
+			dir:      ".",
+			want:     &Pin{Query: "go1.22.3", Directive: "toolchain"},
+			wantFile: "go.mod",
+		},
+		{
+			name:     "default toolchain",
+			files:    map[string]string{"go.mod": "module x\ngo 1.21\ntoolchain default\n"},
+			dir:      ".",
+			want:     &Pin{Query: ">=1.21", Directive: "go", Minimum: true},
+			wantFile: "go.mod",
+		},
+		{
+			name:     "go.work wins over go.mod",
+			files:    map[string]string{"go.work": "go 1.22\n", "go.mod": "module x\ngo 1.21\n"},
+			dir:      ".",
+			want:     &Pin{Query: ">=1.22", Directive: "go", Minimum: true},
+			wantFile: "go.work",
+		},
+		{
+			name:     "go.mod without a go line",
+			files:    map[string]string{".go-version": "1.20\n", "sub/go.mod": "module x\n"},
+			dir:      "sub",
+			want:     &Pin{Query: "1.20"},
+			wantFile: ".go-version",
+		},
+		{
+			name:      "empty go-version",
+			files:     map[string]string{".go-version": "\n"},
+			dir:       ".",
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+			dir := filepath.Join(root, filepath.FromSlash(tt.dir))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			got, err := FindPin(dir)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("FindPin() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.want == nil {
+				if got != nil && !tt.wantError {
+					t.Errorf("FindPin() = %+v, want nil", got)
+				}
+				return
+			}
+			want := *tt.want
+			want.File = filepath.Join(root, tt.wantFile)
+			if got == nil || *got != want {
+				t.Errorf("FindPin() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestPinSatisfiedBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		pin     Pin
+		version string
+		want    bool
+	}{
+		{"newer than the go line", Pin{Query: ">=1.21", Minimum: true}, "go1.22.3", true},
+		{"same minor as the go line", Pin{Query: ">=1.21", Minimum: true}, "go1.21.0", true},
+		{"older than the go line", Pin{Query: ">=1.21", Minimum: true}, "go1.20.14", false},
+		{"patch go line", Pin{Query: ">=1.21.5", Minimum: true}, "go1.21.4", false},
+		{"prerelease of the go line", Pin{Query: ">=1.22", Minimum: true}, "go1.22rc1", false},
+		{"exact pin", Pin{Query: "go1.21.5"}, "go1.21.5", false},
+		{"no version", Pin{Query: ">=1.21", Minimum: true}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pin.SatisfiedBy(tt.version); got != tt.want {
+				t.Errorf("SatisfiedBy(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
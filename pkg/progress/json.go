@@ -0,0 +1,108 @@
+package progress
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// Event is a line written by the JSON reporter
+type Event struct {
+	Time    time.Time `json:"time"`
+	Task    int       `json:"task"`
+	Op      string    `json:"op"`
+	Event   string    `json:"event"`
+	Title   string    `json:"title,omitempty"`
+	Current int64     `json:"current"`
+	Total   int64     `json:"total"`
+	Message string    `json:"message,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Event kinds written by the JSON reporter
+const (
+	EventStart    = "start"
+	EventProgress = "progress"
+	EventDone     = "done"
+	EventFail     = "fail"
+)
+
+// progressStep is the fraction of the total between progress events, so large downloads don't flood the output
+const progressStep = 100
+
+type jsonReporter struct {
+	mu   sync.Mutex
+	enc  *json.Encoder
+	next int
+}
+
+// NewJSON returns a reporter writing every event as a line of JSON to w
+func NewJSON(w io.Writer) Reporter {
+	return &jsonReporter{enc: json.NewEncoder(w)}
+}
+
+func (r *jsonReporter) emit(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.Time = time.Now()
+	if err := r.enc.Encode(e); err != nil {
+		log.Error().Err(err).Msg("failed to write progress event")
+	}
+}
+
+func (r *jsonReporter) Start(op string, title string, total int64) Task {
+	r.mu.Lock()
+	r.next++
+	id := r.next
+	r.mu.Unlock()
+	t := &jsonTask{r: r, id: id, op: op, total: total}
+	r.emit(Event{Task: id, Op: op, Event: EventStart, Title: title, Total: total})
+	return t
+}
+
+type jsonTask struct {
+	r       *jsonReporter
+	mu      sync.Mutex
+	id      int
+	op      string
+	current int64
+	total   int64
+	emitted int64
+}
+
+func (t *jsonTask) event(kind string) Event {
+	return Event{Task: t.id, Op: t.op, Event: kind, Current: t.current, Total: t.total}
+}
+
+func (t *jsonTask) Add(n int64) {
+	t.mu.Lock()
+	t.current += n
+	step := t.total / progressStep
+	if t.current-t.emitted <= step && t.current != t.total {
+		t.mu.Unlock()
+		return
+	}
+	t.emitted = t.current
+	e := t.event(EventProgress)
+	t.mu.Unlock()
+	t.r.emit(e)
+}
+
+func (t *jsonTask) Done(msg string) {
+	t.mu.Lock()
+	e := t.event(EventDone)
+	t.mu.Unlock()
+	e.Message = msg
+	t.r.emit(e)
+}
+
+func (t *jsonTask) Fail(err error) {
+	t.mu.Lock()
+	e := t.event(EventFail)
+	t.mu.Unlock()
+	e.Error = err.Error()
+	t.r.emit(e)
+}
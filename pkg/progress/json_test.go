@@ -0,0 +1,77 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// decodeEvents parses the JSON lines written by a JSON reporter, dropping their times
+func decodeEvents(t *testing.T, b []byte) []Event {
+	t.Helper()
+	var events []Event
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("invalid event %q: %v", sc.Text(), err)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("event %q has no time", sc.Text())
+		}
+		e.Time = time.Time{}
+		events = append(events, e)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJSON(&buf)
+	download := r.Start(OpDownload, "Downloading go1.21.5.linux-amd64.tar.gz", 1000)
+	extract := r.Start(OpExtract, "Extracting go1.21.5.linux-amd64.tar.gz", 0)
+	// additions smaller than a hundredth of the total are only reported once they add up to more
+	for i := 0; i < 4; i++ {
+		download.Add(5)
+	}
+	download.Add(500)
+	download.Add(480)
+	download.Done("")
+	extract.Fail(errors.New("unexpected EOF"))
+	want := []Event{
+		{Task: 1, Op: OpDownload, Event: EventStart, Title: "Downloading go1.21.5.linux-amd64.tar.gz", Total: 1000},
+		{Task: 2, Op: OpExtract, Event: EventStart, Title: "Extracting go1.21.5.linux-amd64.tar.gz"},
+		{Task: 1, Op: OpDownload, Event: EventProgress, Current: 15, Total: 1000},
+		{Task: 1, Op: OpDownload, Event: EventProgress, Current: 520, Total: 1000},
+		{Task: 1, Op: OpDownload, Event: EventProgress, Current: 1000, Total: 1000},
+		{Task: 1, Op: OpDownload, Event: EventDone, Current: 1000, Total: 1000},
+		{Task: 2, Op: OpExtract, Event: EventFail, Error: "unexpected EOF"},
+	}
+	if got := decodeEvents(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestJSONDoneMessage(t *testing.T) {
+	var buf bytes.Buffer
+	task := NewJSON(&buf).Start(OpConfig, "Loading config", 0)
+	task.Add(1)
+	task.Done("Loaded config")
+	got := decodeEvents(t, buf.Bytes())
+	want := []Event{
+		{Task: 1, Op: OpConfig, Event: EventStart, Title: "Loading config"},
+		{Task: 1, Op: OpConfig, Event: EventProgress, Current: 1},
+		{Task: 1, Op: OpConfig, Event: EventDone, Current: 1, Message: "Loaded config"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events =\n%+v\nwant\n%+v", got, want)
+	}
+}
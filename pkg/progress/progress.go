@@ -0,0 +1,69 @@
+// Package progress reports the progress of long-running operations like downloads, extraction and config loading
+// without tying them to a terminal UI.
+package progress
+
+import "sync"
+
+// Operations reported by go-manager
+const (
+	OpDownload = "download"
+	OpExtract  = "extract"
+	OpParse    = "parse"
+	OpConfig   = "config"
+)
+
+// Reporter starts tasks for the operations it's given to
+type Reporter interface {
+	// Start begins a task of the operation op, total is the amount of work in the task's units, or 0 if unknown
+	Start(op string, title string, total int64) Task
+}
+
+// Task is a single running operation, it's safe for concurrent use
+type Task interface {
+	// Add advances the task by n units
+	Add(n int64)
+	// Done ends the task successfully
+	Done(msg string)
+	// Fail ends the task with an error
+	Fail(err error)
+}
+
+var (
+	mu              sync.RWMutex
+	defaultReporter Reporter = Nop
+)
+
+// Default returns the reporter used by operations that aren't given one, Nop unless SetDefault was called
+func Default() Reporter {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultReporter
+}
+
+// SetDefault sets the reporter used by operations that aren't given one, nil resets it to Nop
+func SetDefault(r Reporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	if r == nil {
+		r = Nop
+	}
+	defaultReporter = r
+}
+
+// Or returns r, or the default reporter if r is nil
+func Or(r Reporter) Reporter {
+	if r == nil {
+		return Default()
+	}
+	return r
+}
+
+type nop struct{}
+
+// Nop discards all progress
+var Nop Reporter = nop{}
+
+func (nop) Start(string, string, int64) Task { return nop{} }
+func (nop) Add(int64)                        {}
+func (nop) Done(string)                      {}
+func (nop) Fail(error)                       {}
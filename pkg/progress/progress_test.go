@@ -0,0 +1,45 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestNop(t *testing.T) {
+	task := Nop.Start(OpDownload, "Downloading", 10)
+	if task == nil {
+		t.Fatal("Nop.Start() = nil")
+	}
+	// none of it may panic
+	task.Add(10)
+	task.Done("done")
+	task.Fail(errors.New("failed"))
+}
+
+func TestSetDefault(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+	if Default() != Nop {
+		t.Errorf("Default() = %v, want Nop", Default())
+	}
+	var buf bytes.Buffer
+	r := NewJSON(&buf)
+	SetDefault(r)
+	if Default() != r {
+		t.Errorf("Default() = %v after SetDefault, want the JSON reporter", Default())
+	}
+	if Or(nil) != r {
+		t.Errorf("Or(nil) = %v, want the default reporter", Or(nil))
+	}
+	if Or(Nop) != Nop {
+		t.Errorf("Or(Nop) = %v, want Nop", Or(Nop))
+	}
+	Or(nil).Start(OpParse, "Parsing", 0).Done("")
+	if buf.Len() == 0 {
+		t.Error("the default reporter got no events")
+	}
+	SetDefault(nil)
+	if Default() != Nop {
+		t.Errorf("Default() = %v after SetDefault(nil), want Nop", Default())
+	}
+}
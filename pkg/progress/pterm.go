@@ -0,0 +1,102 @@
+package progress
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"atomicgo.dev/cursor"
+	"github.com/pterm/pterm"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+type ptermReporter struct {
+	w io.Writer
+}
+
+// PTerm shows a progress bar for tasks with a known total and a spinner for the others
+var PTerm Reporter = ptermReporter{}
+
+// NewPTerm returns a PTerm reporter drawing its progress bars and spinners on w instead of stdout.
+// The progress bars hide the cursor through a global target, it's moved to w as well.
+func NewPTerm(w io.Writer) Reporter {
+	if cw, ok := w.(cursor.Writer); ok {
+		cursor.SetTarget(cw)
+	}
+	return ptermReporter{w: w}
+}
+
+func (r ptermReporter) Start(op string, title string, total int64) Task {
+	if total > 0 {
+		bar := pterm.DefaultProgressbar
+		if r.w != nil {
+			bar.Writer = r.w
+		}
+		pb, err := bar.WithTitle(title).WithTotal(int(total)).Start()
+		if err != nil {
+			log.Error().Err(err).Str("Op", op).Msg("failed to start progress bar")
+			return nop{}
+		}
+		return &ptermBar{pb: pb}
+	}
+	spinner := pterm.DefaultSpinner
+	if r.w != nil {
+		spinner.Writer = r.w
+	}
+	sp, err := spinner.WithText(title).WithShowTimer(true).WithTimerRoundingFactor(time.Second).Start()
+	if err != nil {
+		log.Error().Err(err).Str("Op", op).Msg("failed to start spinner")
+		return nop{}
+	}
+	return &ptermSpinner{sp: sp}
+}
+
+type ptermBar struct {
+	mu sync.Mutex
+	pb *pterm.ProgressbarPrinter
+}
+
+func (b *ptermBar) Add(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pb.Add(int(n))
+}
+
+func (b *ptermBar) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.pb.Stop(); err != nil {
+		log.Error().Err(err).Msg("failed to stop progress bar")
+	}
+}
+
+func (b *ptermBar) Done(msg string) {
+	b.stop()
+	if msg != "" {
+		pterm.Success.Println(msg)
+	}
+}
+
+// Fail only stops the bar, the error is left to whoever handles it
+func (b *ptermBar) Fail(error) {
+	b.stop()
+}
+
+type ptermSpinner struct {
+	mu sync.Mutex
+	sp *pterm.SpinnerPrinter
+}
+
+func (s *ptermSpinner) Add(int64) {}
+
+func (s *ptermSpinner) Done(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sp.Success(pterm.Green(msg))
+}
+
+func (s *ptermSpinner) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sp.Fail(pterm.Red(err.Error()))
+}
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/zerolog/log"
+
+	"github.com/x0f5c3/go-manager/pkg/semver"
+)
+
+var ErrNotInstalled = errors.New("toolchain not installed")
+
+// Uninstall removes the toolchain for the given go version from envsDir
+func Uninstall(envsDir string, version string) error {
+	dir := InstallDir(envsDir, version)
+	if _, err := ReadToolchainVersion(dir); err != nil {
+		return errors.Wrap(ErrNotInstalled, version)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrapf(err, "failed to remove %s", dir)
+	}
+	log.Debug().Str("Version", version).Str("Path", dir).Msg("uninstalled toolchain")
+	return nil
+}
+
+// DirSize returns the total size of the regular files under dir
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// PruneCandidates returns the versions to remove so only the keep newest patch releases of every minor line remain.
+// Versions named in pinned are never returned.
+func (v *Versions) PruneCandidates(keep int, pinned ...string) Versions {
+	sorted := make(Versions, len(*v))
+	copy(sorted, *v)
+	sort.Sort(&sorted)
+	isPinned := make(map[string]bool, len(pinned))
+	for _, p := range pinned {
+		isPinned[p] = true
+	}
+	kept := make(map[string]int)
+	var res Versions
+	for _, version := range sorted {
+		line := semver.MajorMinor(semver.FromGo(version.Version))
+		if kept[line] < keep {
+			kept[line]++
+			continue
+		}
+		if isPinned[version.Version] {
+			continue
+		}
+		res = append(res, version)
+	}
+	return res
+}
+
+// OrphanedArchives returns the go release archives and partial downloads left behind in dataDir by interrupted installs
+func OrphanedArchives(dataDir string) ([]string, error) {
+	entries, err := os.ReadDir(dataDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", dataDir)
+	}
+	var res []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, "go") {
+			continue
+		}
+		archive := strings.TrimSuffix(strings.TrimSuffix(name, partStateSuffix), partSuffix)
+		if strings.HasSuffix(archive, ".tar.gz") || strings.HasSuffix(archive, ".zip") {
+			res = append(res, filepath.Join(dataDir, name))
+		}
+	}
+	return res, nil
+}
+
+// ArchiveVersion returns the go version of a release archive or partial download like go1.21.5.linux-amd64.tar.gz.part,
+// or an empty string if path isn't named like one
+func ArchiveVersion(path string) string {
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), partStateSuffix), partSuffix)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".tar.gz"), ".zip")
+	// what's left is the version and the platform, like go1.21.5.linux-amd64
+	i := strings.LastIndex(name, ".")
+	if i <= 0 || !strings.HasPrefix(name, "go") {
+		return ""
+	}
+	return name[:i]
+}
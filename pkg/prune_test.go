@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestArchiveVersion(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"go1.21.5.linux-amd64.tar.gz", "go1.21.5"},
+		{"/data/go1.21.5.linux-amd64.tar.gz.part", "go1.21.5"},
+		{"go1.22rc1.windows-arm64.zip.part.json", "go1.22rc1"},
+		{"go1.20.darwin-arm64.tar.gz", "go1.20"},
+		{"versions.json", ""},
+		{"go.tar.gz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := ArchiveVersion(tt.path); got != tt.want {
+				t.Errorf("ArchiveVersion(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func versionNames(v Versions) []string {
+	var res []string
+	for _, version := range v {
+		res = append(res, version.Version)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestPruneCandidates(t *testing.T) {
+	installed := Versions{
+		{Version: "go1.21.3"}, {Version: "go1.21.4"}, {Version: "go1.21.5"},
+		{Version: "go1.20.13"}, {Version: "go1.20.14"},
+		{Version: "go1.19.1"},
+	}
+	tests := []struct {
+		name   string
+		keep   int
+		pinned []string
+		want   []string
+	}{
+		{"keep one", 1, nil, []string{"go1.20.13", "go1.21.3", "go1.21.4"}},
+		{"keep two", 2, nil, []string{"go1.21.3"}},
+		{"pinned", 1, []string{"go1.21.3", "go1.20.13"}, []string{"go1.21.4"}},
+		{"keep all", 3, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := versionNames(installed.PruneCandidates(tt.keep, tt.pinned...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PruneCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrphanedArchives(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go1.21.5.linux-amd64.tar.gz":           "",
+		"go1.21.6.linux-amd64.tar.gz.part":      "",
+		"go1.21.6.linux-amd64.tar.gz.part.json": "",
+		"gom.toml":                              "",
+		"versions.json":                         "",
+		"cache/go1.20.linux-amd64.tar.gz":       "",
+	})
+	got, err := OrphanedArchives(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "go1.21.5.linux-amd64.tar.gz"),
+		filepath.Join(dir, "go1.21.6.linux-amd64.tar.gz.part"),
+		filepath.Join(dir, "go1.21.6.linux-amd64.tar.gz.part.json"),
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OrphanedArchives() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/x0f5c3/go-manager/pkg/semver"
+)
+
+var ErrNoMatch = errors.New("no version matches the query")
+
+var operatorSpace = regexp.MustCompile(`([<>=!]+)\s+`)
+
+type constraint struct {
+	op      string
+	version string
+}
+
+func (c constraint) match(v string) bool {
+	cmp := semver.Compare(v, c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// toSemver turns a user supplied version like 1.21 or 1.23rc1 into a semver string.
+// Unlike semver.FromGo it keeps missing minor and patch numbers missing so they can be matched as prefixes.
+func toSemver(v string) string {
+	v = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(v), "go"), "v")
+	if v == "" {
+		return ""
+	}
+	if strings.Contains(v, "beta") || strings.Contains(v, "rc") {
+		return semver.FromGo("go" + v)
+	}
+	return "v" + v
+}
+
+func parseConstraints(query string) ([]constraint, error) {
+	var res []constraint
+	query = operatorSpace.ReplaceAllString(query, "$1")
+	for _, field := range strings.FieldsFunc(query, func(r rune) bool { return r == ' ' || r == ',' }) {
+		op := field[:len(field)-len(strings.TrimLeft(field, "<>=!"))]
+		vers := toSemver(strings.TrimPrefix(field, op))
+		if vers == "" || !semver.IsValid(vers) {
+			return nil, errors.Errorf("invalid version %q in query %q", field, query)
+		}
+		switch op {
+		case ">=", "<=", ">", "<", "!=", "=", "==", "":
+		default:
+			return nil, errors.Errorf("invalid operator %q in query %q", op, query)
+		}
+		res = append(res, constraint{op: op, version: vers})
+	}
+	return res, nil
+}
+
+// Resolve returns the newest version matching the query.
+// The query can be one of:
+//   - latest: the newest version
+//   - latest-N: the newest version of the Nth minor line before the latest one, latest-1 is the previous minor line
+//   - a major or major.minor prefix like 1 or 1.22: the newest version in that line
+//   - an exact version like 1.21.5, or a go release name like go1.20
+//   - a space or comma separated list of constraints like >=1.20 <1.22, all of which have to match
+func (v *Versions) Resolve(query string) (*GoVersion, error) {
+	query = strings.TrimSpace(query)
+	sorted := make(Versions, len(*v))
+	copy(sorted, *v)
+	sort.Sort(&sorted)
+	if len(sorted) == 0 {
+		return nil, errors.Wrap(ErrNoMatch, query)
+	}
+	if query == "" || query == "latest" {
+		return sorted[0], nil
+	}
+	if strings.HasPrefix(query, "latest-") {
+		n, err := strconv.Atoi(strings.TrimPrefix(query, "latest-"))
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid query %q", query)
+		}
+		var lines []string
+		for _, version := range sorted {
+			line := semver.MajorMinor(semver.FromGo(version.Version))
+			if len(lines) == 0 || lines[len(lines)-1] != line {
+				lines = append(lines, line)
+			}
+			if len(lines) == n+1 {
+				return version, nil
+			}
+		}
+		return nil, errors.Wrap(ErrNoMatch, query)
+	}
+	if strings.HasPrefix(query, "go") {
+		for _, version := range sorted {
+			if version.Version == query {
+				return version, nil
+			}
+		}
+	}
+	var match func(string) bool
+	if strings.ContainsAny(query, "<>=! ,") {
+		constraints, err := parseConstraints(query)
+		if err != nil {
+			return nil, err
+		}
+		match = func(vers string) bool {
+			for _, c := range constraints {
+				if !c.match(vers) {
+					return false
+				}
+			}
+			return true
+		}
+	} else {
+		wanted := toSemver(query)
+		if wanted == "" || !semver.IsValid(wanted) {
+			return nil, errors.Errorf("invalid query %q", query)
+		}
+		switch strings.Count(wanted, ".") {
+		case 0:
+			match = func(vers string) bool { return semver.Major(vers) == wanted }
+		case 1:
+			match = func(vers string) bool { return semver.MajorMinor(vers) == wanted }
+		default:
+			match = func(vers string) bool { return semver.Compare(vers, wanted) == 0 }
+		}
+	}
+	for _, version := range sorted {
+		vers := semver.FromGo(version.Version)
+		if vers != "" && match(vers) {
+			return version, nil
+		}
+	}
+	return nil, errors.Wrap(ErrNoMatch, query)
+}
+
+// LatestPerMinor returns the names of the newest version of every minor line
+func (v *Versions) LatestPerMinor() map[string]bool {
+	sorted := make(Versions, len(*v))
+	copy(sorted, *v)
+	sort.Sort(&sorted)
+	res := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, version := range sorted {
+		line := semver.MajorMinor(semver.FromGo(version.Version))
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		res[version.Version] = true
+	}
+	return res
+}
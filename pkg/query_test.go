@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func goVersions(names ...string) Versions {
+	var res Versions
+	for _, name := range names {
+		res = append(res, &GoVersion{Version: name})
+	}
+	return res
+}
+
+func TestResolve(t *testing.T) {
+	versions := goVersions("go1.19.1", "go1.20", "go1.20.13", "go1.21.0", "go1.21.4", "go1.21.5", "go1.22rc1")
+	tests := []struct {
+		query     string
+		want      string
+		wantErr   error
+		wantOther bool
+	}{
+		{query: "", want: "go1.22rc1"},
+		{query: "latest", want: "go1.22rc1"},
+		{query: "latest-1", want: "go1.21.5"},
+		{query: "latest-2", want: "go1.20.13"},
+		{query: "latest-9", wantErr: ErrNoMatch},
+		{query: "latest-x", wantOther: true},
+		{query: "1.21", want: "go1.21.5"},
+		{query: " 1.20 ", want: "go1.20.13"},
+		{query: "go1.20", want: "go1.20"},
+		{query: "1.21.4", want: "go1.21.4"},
+		{query: "v1.21.4", want: "go1.21.4"},
+		{query: "go1.21.4", want: "go1.21.4"},
+		{query: "1.22rc1", want: "go1.22rc1"},
+		{query: ">=1.20 <1.21", want: "go1.20.13"},
+		{query: ">= 1.20, < 1.21", want: "go1.20.13"},
+		{query: "<1.20", want: "go1.19.1"},
+		{query: "!=1.21.5 <1.21.9", want: "go1.21.4"},
+		{query: "1.18", wantErr: ErrNoMatch},
+		{query: ">1.23", wantErr: ErrNoMatch},
+		{query: "~1.21", wantOther: true},
+		{query: "=>1.21", wantOther: true},
+		{query: ">=x", wantOther: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := versions.Resolve(tt.query)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Resolve(%q) error = %v, want %v", tt.query, err, tt.wantErr)
+				}
+			case tt.wantOther:
+				if err == nil || errors.Is(err, ErrNoMatch) {
+					t.Errorf("Resolve(%q) error = %v, want an invalid query", tt.query, err)
+				}
+			case err != nil:
+				t.Errorf("Resolve(%q) error = %v", tt.query, err)
+			case got.Version != tt.want:
+				t.Errorf("Resolve(%q) = %s, want %s", tt.query, got.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveEmpty(t *testing.T) {
+	var versions Versions
+	if _, err := versions.Resolve("latest"); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Resolve() error = %v, want %v", err, ErrNoMatch)
+	}
+}
+
+func TestLatestPerMinor(t *testing.T) {
+	versions := goVersions("go1.20.12", "go1.21.5", "go1.20.13", "go1.21rc2", "go1.19")
+	want := map[string]bool{"go1.21.5": true, "go1.20.13": true, "go1.19": true}
+	if got := versions.LatestPerMinor(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LatestPerMinor() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SetEnv returns env with the variable name set to value, names are case insensitive on Windows
+func SetEnv(env []string, name, value string) []string {
+	res := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		k, _, _ := strings.Cut(kv, "=")
+		if k == name || runtime.GOOS == "windows" && strings.EqualFold(k, name) {
+			continue
+		}
+		res = append(res, kv)
+	}
+	return append(res, name+"="+value)
+}
+
+// GetEnv returns the value of the variable name in env
+func GetEnv(env []string, name string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		k, v, _ := strings.Cut(env[i], "=")
+		if k == name || runtime.GOOS == "windows" && strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// RunCommand runs the program name found on the PATH of env with args and the standard streams of go-manager.
+// The signals go-manager receives meanwhile are passed on to it, its exit code is returned.
+func RunCommand(name string, args []string, env []string) (int, error) {
+	path, err := lookPath(name, env)
+	if err != nil {
+		return 127, err
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = env
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+	if err := cmd.Start(); err != nil {
+		return 126, errors.Wrapf(err, "failed to run %s", path)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				forwardSignal(cmd.Process, sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	err = cmd.Wait()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exitCode(exit), nil
+	}
+	if err != nil {
+		return 1, errors.Wrapf(err, "failed to run %s", path)
+	}
+	return 0, nil
+}
+
+// lookPath finds the program name in the directories of the PATH in env rather than the one of go-manager,
+// trying the extensions in PATHEXT on Windows. A name with a separator is used as is.
+// Relative directories are skipped like exec.LookPath does since go 1.19.
+// It doesn't touch the environment of go-manager, so jobs with their own PATH can look up programs in parallel.
+func lookPath(name string, env []string) (string, error) {
+	exts := executableExts(env)
+	if strings.ContainsAny(name, `/`+string(filepath.Separator)) {
+		if path, ok := findExecutable(name, exts); ok {
+			return path, nil
+		}
+		return "", errors.Errorf("%s not found", name)
+	}
+	for _, dir := range filepath.SplitList(GetEnv(env, "PATH")) {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		if path, ok := findExecutable(filepath.Join(dir, name), exts); ok {
+			return path, nil
+		}
+	}
+	return "", errors.Errorf("%s not found in PATH", name)
+}
+
+// findExecutable returns path, or path with one of exts appended, if it's an executable file
+func findExecutable(path string, exts []string) (string, bool) {
+	if len(exts) == 0 {
+		return path, isExecutable(path)
+	}
+	for _, ext := range exts {
+		if strings.EqualFold(filepath.Ext(path), ext) && isExecutable(path) {
+			return path, true
+		}
+	}
+	for _, ext := range exts {
+		if isExecutable(path + ext) {
+			return path + ext, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeScript writes a shell script called name to dir
+func writeScript(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses executable bits")
+	}
+	root := t.TempDir()
+	first, second, plain := filepath.Join(root, "first"), filepath.Join(root, "second"), filepath.Join(root, "plain")
+	writeScript(t, first, "go", "", 0755)
+	writeScript(t, second, "go", "", 0755)
+	writeScript(t, second, "gofmt", "", 0755)
+	writeScript(t, plain, "gofmt", "", 0644)
+	if err := os.MkdirAll(filepath.Join(plain, "vet"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		prog    string
+		path    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "first match", prog: "go", path: []string{first, second}, want: filepath.Join(first, "go")},
+		{name: "later dir", prog: "gofmt", path: []string{first, second}, want: filepath.Join(second, "gofmt")},
+		{name: "not executable", prog: "gofmt", path: []string{plain, second}, want: filepath.Join(second, "gofmt")},
+		{name: "directory", prog: "vet", path: []string{plain}, wantErr: true},
+		{name: "relative dir", prog: "go", path: []string{"first", second}, want: filepath.Join(second, "go")},
+		{name: "with a separator", prog: filepath.Join(first, "go"), path: nil, want: filepath.Join(first, "go")},
+		{name: "missing", prog: "godoc", path: []string{first, second}, wantErr: true},
+		{name: "empty PATH", prog: "go", wantErr: true},
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := SetEnv(os.Environ(), "PATH", strings.Join(tt.path, string(os.PathListSeparator)))
+			before := os.Getenv("PATH")
+			got, err := lookPath(tt.prog, env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("lookPath() = %s, want %s", got, tt.want)
+			}
+			if os.Getenv("PATH") != before {
+				t.Error("lookPath() changed the PATH of the process")
+			}
+		})
+	}
+}
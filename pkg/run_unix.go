@@ -0,0 +1,35 @@
+//go:build !windows
+
+package pkg
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// forwardedSignals are passed on to the child processes of RunCommand
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+func forwardSignal(p *os.Process, sig os.Signal) {
+	_ = p.Signal(sig)
+}
+
+// exitCode returns the exit code of a child, a child killed by a signal exits with 128 plus the signal like in shells
+func exitCode(exit *exec.ExitError) int {
+	if ws, ok := exit.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return exit.ExitCode()
+}
+
+// executableExts returns the extensions tried by lookPath, none outside of Windows
+func executableExts([]string) []string {
+	return nil
+}
+
+// isExecutable reports whether path is a file with an executable bit set
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir() && fi.Mode()&0111 != 0
+}
@@ -0,0 +1,67 @@
+//go:build !windows
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunCommandExitCode(t *testing.T) {
+	bin := t.TempDir()
+	env := SetEnv(os.Environ(), "PATH", bin)
+	writeScript(t, bin, "pass", "exit 0\n", 0755)
+	writeScript(t, bin, "fail", "exit 3\n", 0755)
+	writeScript(t, bin, "killed", "kill -KILL $$\n", 0755)
+	writeScript(t, bin, "args", `[ "$1 $2" = "vet ./..." ] || exit 9`+"\n", 0755)
+	tests := []struct {
+		name    string
+		args    []string
+		want    int
+		wantErr bool
+	}{
+		{name: "pass", want: 0},
+		{name: "fail", want: 3},
+		{name: "killed", want: 128 + int(syscall.SIGKILL)},
+		{name: "args", args: []string{"vet", "./..."}, want: 0},
+		{name: "missing", want: 127, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RunCommand(tt.name, tt.args, env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RunCommand() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCommandForwardsSignals(t *testing.T) {
+	dir := t.TempDir()
+	ready := filepath.Join(dir, "ready")
+	writeScript(t, dir, "child", "sleep 10 &\ntrap 'kill $!; exit 42' TERM\ntouch "+ready+"\nwait\nexit 1\n", 0755)
+	go func() {
+		// the signal is only sent once the child is running and forwarding is set up
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, err := os.Stat(ready); err == nil {
+				_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	got, err := RunCommand("child", nil, SetEnv(os.Environ(), "PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 42 {
+		t.Errorf("RunCommand() = %d, want the 42 the child exits with on SIGTERM", got)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package pkg
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// defaultPathExt is used when PATHEXT isn't set, like cmd.exe does
+const defaultPathExt = ".com;.exe;.bat;.cmd"
+
+// forwardedSignals are caught while RunCommand waits, the child gets console interrupts itself
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// forwardSignal does nothing, interrupts can't be sent to other processes on Windows
+func forwardSignal(*os.Process, os.Signal) {}
+
+func exitCode(exit *exec.ExitError) int {
+	return exit.ExitCode()
+}
+
+// executableExts returns the extensions in the PATHEXT of env tried by lookPath
+func executableExts(env []string) []string {
+	pathExt := GetEnv(env, "PATHEXT")
+	if pathExt == "" {
+		pathExt = defaultPathExt
+	}
+	var exts []string
+	for _, ext := range strings.Split(strings.ToLower(pathExt), ";") {
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// isExecutable reports whether path is a file, which extension makes it executable is up to executableExts
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
@@ -0,0 +1,229 @@
+package semver
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+)
+
+var (
+	_ pflag.Value = (*Release)(nil)
+	_ pflag.Value = (*Version)(nil)
+)
+
+// prereleaseRank orders the prerelease kinds of go releases, final releases rank highest
+var prereleaseRank = map[string]int{
+	"beta": 0,
+	"rc":   1,
+	"":     2,
+}
+
+// Release is a go release as named by go.dev, like go1, go1.20, go1.21.5, go1.22beta1 or go1.21rc2
+type Release struct {
+	Major int
+	Minor int
+	Patch int
+	// Pre is the prerelease kind, beta or rc, and empty for final releases
+	Pre string
+	// PreNum is the number of the beta or release candidate
+	PreNum int
+	// parts is the number of dot separated numbers in the name, so go1.20 doesn't turn into go1.20.0
+	parts int
+}
+
+// ParseRelease parses a go release name.
+// A leading v is accepted in place of go so semantic versions of final releases, like v1.21.5, parse as well.
+func ParseRelease(name string) (*Release, error) {
+	orig := name
+	switch {
+	case strings.HasPrefix(name, "go"):
+		name = name[2:]
+	case strings.HasPrefix(name, "v"):
+		name = name[1:]
+	default:
+		return nil, fmt.Errorf("invalid go release %q", orig)
+	}
+	var r Release
+	for _, kind := range []string{"beta", "rc"} {
+		i := strings.Index(name, kind)
+		if i <= 0 {
+			continue
+		}
+		num, ok := parseReleaseNum(name[i+len(kind):])
+		if !ok || num == 0 {
+			return nil, fmt.Errorf("invalid go release %q", orig)
+		}
+		r.Pre, r.PreNum = kind, num
+		name = name[:i]
+		break
+	}
+	parts := strings.Split(name, ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid go release %q", orig)
+	}
+	nums := []*int{&r.Major, &r.Minor, &r.Patch}
+	for i, part := range parts {
+		num, ok := parseReleaseNum(part)
+		if !ok {
+			return nil, fmt.Errorf("invalid go release %q", orig)
+		}
+		*nums[i] = num
+	}
+	r.parts = len(parts)
+	return &r, nil
+}
+
+func parseReleaseNum(s string) (int, bool) {
+	if s == "" || !isNum(s) || isBadNum(s) {
+		return 0, false
+	}
+	num, err := strconv.Atoi(s)
+	return num, err == nil
+}
+
+// IsValidRelease reports whether name is a valid go release name
+func IsValidRelease(name string) bool {
+	_, err := ParseRelease(name)
+	return err == nil
+}
+
+// String returns the go release name, exactly as it was parsed
+func (r *Release) String() string {
+	if r == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("go")
+	b.WriteString(strconv.Itoa(r.Major))
+	if r.parts > 1 || r.Minor != 0 || r.Patch != 0 {
+		b.WriteString("." + strconv.Itoa(r.Minor))
+	}
+	if r.parts > 2 || r.Patch != 0 {
+		b.WriteString("." + strconv.Itoa(r.Patch))
+	}
+	if r.Pre != "" {
+		b.WriteString(r.Pre + strconv.Itoa(r.PreNum))
+	}
+	return b.String()
+}
+
+// Semver returns the canonical semantic version of the release, with betas and release candidates as prereleases.
+// For example go1.21rc2 becomes v1.21.0-rc.2 and go1.20 becomes v1.20.0.
+func (r *Release) Semver() string {
+	res := fmt.Sprintf("v%d.%d.%d", r.Major, r.Minor, r.Patch)
+	if r.Pre != "" {
+		res += fmt.Sprintf("-%s.%d", r.Pre, r.PreNum)
+	}
+	return res
+}
+
+// IsStable reports whether the release is a final release
+func (r *Release) IsStable() bool {
+	return r.Pre == ""
+}
+
+// MajorMinor returns the minor line of the release, like go1.21
+func (r *Release) MajorMinor() string {
+	return fmt.Sprintf("go%d.%d", r.Major, r.Minor)
+}
+
+// Compare returns an integer comparing two releases the way go.dev orders them.
+// The result will be 0 if r == o, -1 if r < o, or +1 if r > o.
+// Betas come before release candidates, which come before the final release, go1.20 and go1.20.0 are equal.
+func (r *Release) Compare(o *Release) int {
+	for _, c := range [][2]int{
+		{r.Major, o.Major},
+		{r.Minor, o.Minor},
+		{r.Patch, o.Patch},
+		{prereleaseRank[r.Pre], prereleaseRank[o.Pre]},
+		{r.PreNum, o.PreNum},
+	} {
+		if c[0] < c[1] {
+			return -1
+		}
+		if c[0] > c[1] {
+			return +1
+		}
+	}
+	return 0
+}
+
+// CompareGo compares two go release names like Compare.
+// An invalid release name is considered less than a valid one, all invalid names compare equal to each other.
+func CompareGo(v, w string) int {
+	rv, errV := ParseRelease(v)
+	rw, errW := ParseRelease(w)
+	switch {
+	case errV != nil && errW != nil:
+		return 0
+	case errV != nil:
+		return -1
+	case errW != nil:
+		return +1
+	}
+	return rv.Compare(rw)
+}
+
+// Set implements pflag.Value
+func (r *Release) Set(s string) error {
+	parsed, err := ParseRelease(s)
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
+
+// Type implements pflag.Value
+func (r *Release) Type() string {
+	return "goversion"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r *Release) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *Release) UnmarshalText(text []byte) error {
+	return r.Set(string(text))
+}
+
+// DecodeHook returns a mapstructure decode hook turning strings into Release and Version values or pointers.
+// An empty string decodes to a nil pointer.
+func DecodeHook() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		s, ok := data.(string)
+		if !ok || f.Kind() != reflect.String {
+			return data, nil
+		}
+		var parse func(string) (interface{}, error)
+		switch t {
+		case reflect.TypeOf(Release{}), reflect.TypeOf(&Release{}):
+			parse = func(s string) (interface{}, error) { return ParseRelease(s) }
+		case reflect.TypeOf(Version{}), reflect.TypeOf(&Version{}):
+			parse = func(s string) (interface{}, error) { return Parse(s) }
+		default:
+			return data, nil
+		}
+		if s == "" {
+			if t.Kind() == reflect.Ptr {
+				// mapstructure allocates a value for a typed nil pointer, nil leaves the field alone
+				return nil, nil
+			}
+			return reflect.New(t).Elem().Interface(), nil
+		}
+		parsed, err := parse(s)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind() == reflect.Ptr {
+			return parsed, nil
+		}
+		return reflect.ValueOf(parsed).Elem().Interface(), nil
+	}
+}
@@ -0,0 +1,138 @@
+package semver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+func TestParseRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Release
+		wantErr bool
+	}{
+		{name: "go1", want: Release{Major: 1, parts: 1}},
+		{name: "go1.20", want: Release{Major: 1, Minor: 20, parts: 2}},
+		{name: "go1.21.5", want: Release{Major: 1, Minor: 21, Patch: 5, parts: 3}},
+		{name: "go1.22beta1", want: Release{Major: 1, Minor: 22, Pre: "beta", PreNum: 1, parts: 2}},
+		{name: "go1.21rc2", want: Release{Major: 1, Minor: 21, Pre: "rc", PreNum: 2, parts: 2}},
+		{name: "v1.21.5", want: Release{Major: 1, Minor: 21, Patch: 5, parts: 3}},
+		{name: "1.21.5", wantErr: true},
+		{name: "go", wantErr: true},
+		{name: "go1.21.5.1", wantErr: true},
+		{name: "go1.21rc", wantErr: true},
+		{name: "go1.21rc0", wantErr: true},
+		{name: "go1.021", wantErr: true},
+		{name: "go1.x", wantErr: true},
+		{name: "go1..5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRelease(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRelease(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != tt.want {
+				t.Errorf("ParseRelease(%q) = %+v, want %+v", tt.name, *got, tt.want)
+			}
+			if IsValidRelease(tt.name) != !tt.wantErr {
+				t.Errorf("IsValidRelease(%q) = %v", tt.name, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReleaseString(t *testing.T) {
+	tests := []struct {
+		name       string
+		want       string
+		wantSemver string
+	}{
+		{"go1", "go1", "v1.0.0"},
+		{"go1.20", "go1.20", "v1.20.0"},
+		{"go1.20.0", "go1.20.0", "v1.20.0"},
+		{"go1.21.5", "go1.21.5", "v1.21.5"},
+		{"go1.21rc2", "go1.21rc2", "v1.21.0-rc.2"},
+		{"v1.21.5", "go1.21.5", "v1.21.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRelease(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got := r.Semver(); got != tt.wantSemver {
+				t.Errorf("Semver() = %q, want %q", got, tt.wantSemver)
+			}
+		})
+	}
+}
+
+func TestCompareGo(t *testing.T) {
+	tests := []struct {
+		v, w string
+		want int
+	}{
+		{"go1.21.5", "go1.21.5", 0},
+		{"go1.20", "go1.20.0", 0},
+		{"go1.21.10", "go1.21.9", 1},
+		{"go1.9", "go1.10", -1},
+		{"go1.21beta1", "go1.21rc1", -1},
+		{"go1.21rc1", "go1.21rc2", -1},
+		{"go1.21rc2", "go1.21", -1},
+		{"go1.21.0", "go1.21rc2", 1},
+		{"go1.22beta1", "go1.21.13", 1},
+		{"invalid", "go1", -1},
+		{"go1", "invalid", 1},
+		{"invalid", "also invalid", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.v+" "+tt.w, func(t *testing.T) {
+			if got := CompareGo(tt.v, tt.w); got != tt.want {
+				t.Errorf("CompareGo(%q, %q) = %d, want %d", tt.v, tt.w, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeHook(t *testing.T) {
+	type config struct {
+		Current *Release `mapstructure:"current"`
+		Value   Release  `mapstructure:"value"`
+	}
+	tests := []struct {
+		name    string
+		input   map[string]interface{}
+		want    config
+		wantErr bool
+	}{
+		{"release", map[string]interface{}{"current": "go1.21.5", "value": "go1.20"},
+			config{Current: &Release{Major: 1, Minor: 21, Patch: 5, parts: 3}, Value: Release{Major: 1, Minor: 20, parts: 2}}, false},
+		{"empty", map[string]interface{}{"current": ""}, config{}, false},
+		{"invalid", map[string]interface{}{"current": "1.21"}, config{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got config
+			dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{DecodeHook: DecodeHook(), Result: &got})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dec.Decode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,17 +15,41 @@ type Version struct {
 	build      string
 }
 
-func (p *Version) Set(s string) (err error) {
-	p, err = Parse(s)
-	return
+func (p *Version) Set(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*p = *parsed
+	return nil
 }
 
 func (p *Version) Type() string {
 	return "semver"
 }
 
+// ParseFromGo parses a go release name like go1.21rc2 into its semantic version, see FromGo
 func ParseFromGo(vers string) (*Version, error) {
-	return Parse(strings.ReplaceAll(vers, "go", "v"))
+	r, err := ParseRelease(vers)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(r.Semver())
+}
+
+// FromGo converts a go release name like go1.21.5, go1.20 or go1.21rc2 into a canonical semantic version.
+// Missing minor and patch numbers are filled in with zeros and betaN and rcN suffixes become -beta.N and -rc.N
+// prereleases, so go1.21rc2 sorts after go1.21rc1 and before go1.21.0.
+// If name is not a valid go release name, FromGo returns the empty string.
+func FromGo(name string) string {
+	if !strings.HasPrefix(name, "go") {
+		return ""
+	}
+	r, err := ParseRelease(name)
+	if err != nil {
+		return ""
+	}
+	return r.Semver()
 }
 
 func Parse(vers string) (*Version, error) {
@@ -176,7 +200,7 @@ func Sort(list []string) {
 }
 
 func parse(v string) (p Version, ok bool) {
-	if v[0] == 'g' && v[1] == 'o' {
+	if len(v) > 1 && v[0] == 'g' && v[1] == 'o' {
 		v = strings.ReplaceAll(v, "go", "v")
 	}
 	if v == "" || v[0] != 'v' {
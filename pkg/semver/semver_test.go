@@ -0,0 +1,40 @@
+package semver
+
+import "testing"
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"go1.21.5", "v1.21.5"},
+		{"go1.20", "v1.20.0"},
+		{"go1", "v1.0.0"},
+		{"go1.21rc2", "v1.21.0-rc.2"},
+		{"go1.22beta1", "v1.22.0-beta.1"},
+		{"go1.21.5rc1", "v1.21.5-rc.1"},
+		{"1.21.5", ""},
+		{"go1.21.5.1", ""},
+		{"go1.21rc", ""},
+		{"go1.21rc01", ""},
+		{"go1.x", ""},
+		{"go", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromGo(tt.name); got != tt.want {
+				t.Errorf("FromGo(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareFromGo(t *testing.T) {
+	// oldest first
+	names := []string{"go1.20", "go1.20.1", "go1.21beta1", "go1.21rc1", "go1.21rc2", "go1.21.0", "go1.21.10"}
+	for i := 1; i < len(names); i++ {
+		if cmp := Compare(FromGo(names[i-1]), FromGo(names[i])); cmp != -1 {
+			t.Errorf("Compare(%s, %s) = %d, want -1", names[i-1], names[i], cmp)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Shell writes changes to the environment in the syntax of a shell
+type Shell interface {
+	// Name is the name the shell is selected by
+	Name() string
+	// Export sets the variable name to value
+	Export(name, value string) string
+	// Unset removes the variable name
+	Unset(name string) string
+	// PrependPath puts dir in front of PATH unless it's already in it
+	PrependPath(dir string) string
+	// RemovePath removes dir from PATH
+	RemovePath(dir string) string
+	// Completion loads the completions of the program at path, it's empty if the shell has none
+	Completion(path string) string
+	// Hook runs hook-env of the program at path before every prompt, it's empty if the shell has none
+	Hook(path string) string
+}
+
+// Variables the directory hook keeps its state in, see the Hook method of the shells
+const (
+	// HookDirEnv is the directory the environment was last set up for
+	HookDirEnv = "_GOM_HOOK_DIR"
+	// HookPinEnv is the file pinning the version in that directory
+	HookPinEnv = "_GOM_HOOK_PIN"
+	// HookModTimeEnv is the modification time of the pin file in nanoseconds
+	HookModTimeEnv = "_GOM_HOOK_MTIME"
+	// HookBinEnv is the bin directory of the pinned toolchain put on PATH
+	HookBinEnv = "_GOM_HOOK_BIN"
+)
+
+// Shells are the supported shells by name
+var Shells = map[string]Shell{
+	"bash":       posixShell{name: "bash"},
+	"zsh":        posixShell{name: "zsh"},
+	"sh":         posixShell{name: "sh"},
+	"fish":       fishShell{},
+	"powershell": powerShell{},
+}
+
+// ShellNames returns the names of the supported shells, sorted
+func ShellNames() []string {
+	res := make([]string, 0, len(Shells))
+	for name := range Shells {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// GetShell returns the shell called name, pwsh is accepted for powershell
+func GetShell(name string) (Shell, error) {
+	if name == "pwsh" {
+		name = "powershell"
+	}
+	sh, ok := Shells[name]
+	if !ok {
+		return nil, errors.Errorf("unsupported shell %q, use one of %s", name, strings.Join(ShellNames(), ", "))
+	}
+	return sh, nil
+}
+
+// EnvVar is an environment variable, an empty value unsets it
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ShellEnv is the environment go-manager sets up in a shell
+type ShellEnv struct {
+	// Path is put in front of PATH, in this order
+	Path []string `json:"path"`
+	Vars []EnvVar `json:"vars"`
+}
+
+// Script returns the lines applying the environment in sh
+func (e *ShellEnv) Script(sh Shell) string {
+	var b strings.Builder
+	for i := len(e.Path) - 1; i >= 0; i-- {
+		b.WriteString(sh.PrependPath(e.Path[i]) + "\n")
+	}
+	for _, v := range e.Vars {
+		if v.Value == "" {
+			b.WriteString(sh.Unset(v.Name) + "\n")
+		} else {
+			b.WriteString(sh.Export(v.Name, v.Value) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// UnsetScript returns the lines undoing the environment in sh
+func (e *ShellEnv) UnsetScript(sh Shell) string {
+	var b strings.Builder
+	for _, dir := range e.Path {
+		b.WriteString(sh.RemovePath(dir) + "\n")
+	}
+	for _, v := range e.Vars {
+		b.WriteString(sh.Unset(v.Name) + "\n")
+	}
+	return b.String()
+}
+
+// InitScript returns the snippet integrating the program at path into sh:
+// the environment, the prompt hook switching to pinned toolchains and the completions
+func (e *ShellEnv) InitScript(sh Shell, path string) string {
+	var b strings.Builder
+	b.WriteString(e.Script(sh))
+	if hook := sh.Hook(path); hook != "" {
+		b.WriteString(hook + "\n")
+	}
+	if completion := sh.Completion(path); completion != "" {
+		b.WriteString(completion + "\n")
+	}
+	return b.String()
+}
+
+// posixQuote quotes s for POSIX shells
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// posixShell covers sh and the shells compatible with it
+type posixShell struct {
+	name string
+}
+
+func (s posixShell) Name() string { return s.name }
+
+func (posixShell) Export(name, value string) string {
+	return fmt.Sprintf("export %s=%s", name, posixQuote(value))
+}
+
+func (posixShell) Unset(name string) string { return "unset " + name }
+
+func (posixShell) PrependPath(dir string) string { return UnixPathSetter(dir) }
+
+func (posixShell) RemovePath(dir string) string { return UnixPathUnsetter(dir) }
+
+// posixHook is the hook of bash and zsh, hook-env only runs once the directory changed or the pin file is newer
+// than the stamp hook-env leaves for the shell. %[1]s is the quoted program, %[2]s the shell.
+const posixHook = `_GOM_HOOK_STAMP="${TMPDIR:-/tmp}/gom-hook-$$"
+_gom_hook() {
+  local ret=$?
+  if [ "$PWD" != "${_GOM_HOOK_DIR-}" ] || { [ -n "${_GOM_HOOK_PIN-}" ] && [ "$_GOM_HOOK_PIN" -nt "$_GOM_HOOK_STAMP" ]; }; then
+    eval "$(GOM_SKIP_DETECT=1 %[1]s hook-env --shell %[2]s --stamp "$_GOM_HOOK_STAMP")"
+  fi
+  return $ret
+}
+`
+
+func (s posixShell) Hook(path string) string {
+	switch s.name {
+	case "bash":
+		return fmt.Sprintf(posixHook, posixQuote(path), s.name) +
+			`if [[ ";${PROMPT_COMMAND:-};" != *";_gom_hook;"* ]]; then PROMPT_COMMAND="_gom_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"; fi`
+	case "zsh":
+		return fmt.Sprintf(posixHook, posixQuote(path), s.name) +
+			"autoload -Uz add-zsh-hook\nadd-zsh-hook precmd _gom_hook"
+	}
+	return ""
+}
+
+func (s posixShell) Completion(path string) string {
+	switch s.name {
+	case "bash":
+		return fmt.Sprintf("source <(%s completion bash)", posixQuote(path))
+	case "zsh":
+		// compdef is only there once compinit ran
+		return fmt.Sprintf("(( $+functions[compdef] )) && source <(%s completion zsh)", posixQuote(path))
+	}
+	return ""
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+type fishShell struct{}
+
+func (fishShell) Name() string { return "fish" }
+
+func (fishShell) Export(name, value string) string {
+	return fmt.Sprintf("set -gx %s %s", name, fishQuote(value))
+}
+
+func (fishShell) Unset(name string) string { return "set -e " + name }
+
+func (fishShell) PrependPath(dir string) string {
+	q := fishQuote(dir)
+	return fmt.Sprintf("contains -- %s $PATH; or set -gx PATH %s $PATH", q, q)
+}
+
+func (fishShell) RemovePath(dir string) string {
+	return fmt.Sprintf("set -gx PATH (string match -v -- %s $PATH)", fishQuote(dir))
+}
+
+func (fishShell) Hook(path string) string {
+	return fmt.Sprintf(`set -g _GOM_HOOK_STAMP (set -q TMPDIR; and echo $TMPDIR; or echo /tmp)/gom-hook-$fish_pid
+function _gom_hook --on-event fish_prompt
+    if test "$PWD" != "$_GOM_HOOK_DIR"; or begin; test -n "$_GOM_HOOK_PIN"; and command test "$_GOM_HOOK_PIN" -nt "$_GOM_HOOK_STAMP"; end
+        env GOM_SKIP_DETECT=1 %s hook-env --shell fish --stamp "$_GOM_HOOK_STAMP" | source
+    end
+end`, fishQuote(path))
+}
+
+func (fishShell) Completion(path string) string {
+	return fmt.Sprintf("%s completion fish | source", fishQuote(path))
+}
+
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+type powerShell struct{}
+
+func (powerShell) Name() string { return "powershell" }
+
+func (powerShell) Export(name, value string) string {
+	return fmt.Sprintf("$env:%s = %s", name, powerShellQuote(value))
+}
+
+func (powerShell) Unset(name string) string {
+	return fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue", name)
+}
+
+func (powerShell) PrependPath(dir string) string {
+	q := powerShellQuote(dir)
+	return fmt.Sprintf("if (-not (($env:PATH -split [IO.Path]::PathSeparator) -contains %s)) "+
+		"{ $env:PATH = %s + [IO.Path]::PathSeparator + $env:PATH }", q, q)
+}
+
+func (powerShell) RemovePath(dir string) string {
+	return fmt.Sprintf("$env:PATH = (($env:PATH -split [IO.Path]::PathSeparator) | Where-Object { $_ -ne %s }) "+
+		"-join [IO.Path]::PathSeparator", powerShellQuote(dir))
+}
+
+func (powerShell) Hook(string) string { return "" }
+
+func (powerShell) Completion(path string) string {
+	return fmt.Sprintf("& %s completion powershell | Out-String | Invoke-Expression", powerShellQuote(path))
+}
@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenEnv has paths with spaces, quotes and characters special to the shells
+var goldenEnv = &ShellEnv{
+	Path: []string{`/home/j doe/.gom/envs/bin`, `/home/j doe/it's "go"/$HOME\bin`},
+	Vars: []EnvVar{
+		{Name: "GOROOT", Value: `/home/j doe/it's "go"/$HOME\`},
+		{Name: "GOPATH", Value: `/home/j doe/go path`},
+		{Name: "GOBIN"},
+	},
+}
+
+// goldenProgram is the path of go-manager in the hooks and completions
+const goldenProgram = `/opt/go manager/it's/go-manager`
+
+// checkGolden compares got to testdata/name, or writes it there with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs, run the tests with -update if it's intended\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestShellGolden(t *testing.T) {
+	for _, name := range ShellNames() {
+		sh := Shells[name]
+		tests := []struct {
+			kind string
+			got  string
+		}{
+			{"env", goldenEnv.Script(sh)},
+			{"env-unset", goldenEnv.UnsetScript(sh)},
+			{"shell-init", goldenEnv.InitScript(sh, goldenProgram)},
+		}
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.kind, func(t *testing.T) {
+				checkGolden(t, name+"."+tt.kind+".golden", tt.got)
+			})
+		}
+	}
+}
+
+// TestPosixEnvScript evaluates the env and unset scripts in the POSIX shells found on PATH to check the quoting
+func TestPosixEnvScript(t *testing.T) {
+	for _, name := range []string{"sh", "bash", "zsh"} {
+		t.Run(name, func(t *testing.T) {
+			path, err := exec.LookPath(name)
+			if err != nil {
+				t.Skipf("%s not found", name)
+			}
+			script := goldenEnv.Script(Shells[name]) + `printf '%s\n' "$PATH" "$GOROOT" "$GOPATH" "${GOBIN-unset}"` + "\n" +
+				goldenEnv.UnsetScript(Shells[name]) + `printf '%s\n' "$PATH" "${GOROOT-unset}"` + "\n"
+			cmd := exec.Command(path, "-c", script)
+			cmd.Env = []string{"PATH=/usr/bin:/bin", "GOBIN=/tmp/bin"}
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{
+				goldenEnv.Path[0] + ":" + goldenEnv.Path[1] + ":/usr/bin:/bin",
+				goldenEnv.Vars[0].Value,
+				goldenEnv.Vars[1].Value,
+				"unset",
+				"/usr/bin:/bin",
+				"unset",
+			}
+			if got := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("%s got\n%s\nwant\n%s", name, strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// ShimNames are the toolchain binaries dispatched by shims
+var ShimNames = []string{"go", "gofmt"}
+
+// exeSuffix is the suffix of executables on this platform
+var exeSuffix = func() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}()
+
+// ShimName returns the toolchain binary a shim started as arg0 dispatches to, and whether arg0 is a shim at all
+func ShimName(arg0 string) (string, bool) {
+	name := strings.TrimSuffix(filepath.Base(arg0), exeSuffix)
+	for _, shim := range ShimNames {
+		if name == shim {
+			return shim, true
+		}
+	}
+	return "", false
+}
+
+// ToolchainBinary returns the path of the binary name in the toolchain installed in goroot
+func ToolchainBinary(goroot, name string) string {
+	return filepath.Join(goroot, "bin", name+exeSuffix)
+}
+
+// InstallShims links the shims into dir, pointing at the go-manager executable target.
+// Where symlinks aren't available the executable is copied instead. Existing files in dir are replaced.
+func InstallShims(dir, target string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s directory", dir)
+	}
+	var res []string
+	for _, name := range ShimNames {
+		path := filepath.Join(dir, name+exeSuffix)
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return res, errors.Wrapf(err, "failed to replace %s", path)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			log.Debug().Err(err).Str("Path", path).Msg("failed to symlink the shim, copying it")
+			if err := copyExecutable(target, path); err != nil {
+				return res, errors.Wrapf(err, "failed to create the shim %s", path)
+			}
+		}
+		res = append(res, path)
+	}
+	return res, nil
+}
+
+// RemoveShims removes the shims from dir, returning the paths removed
+func RemoveShims(dir string) ([]string, error) {
+	var res []string
+	for _, name := range ShimNames {
+		path := filepath.Join(dir, name+exeSuffix)
+		err := os.Remove(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return res, errors.Wrapf(err, "failed to remove %s", path)
+		}
+		res = append(res, path)
+	}
+	return res, nil
+}
+
+func copyExecutable(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func(in *os.File) {
+		err := in.Close()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to close %s", in.Name())
+		}
+	}(in)
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ToolchainEnv returns env with GOROOT pointing at goroot.
+// GOTOOLCHAIN defaults to local so the go command doesn't switch away from the toolchain that was picked for it.
+func ToolchainEnv(env []string, goroot string) []string {
+	res := make([]string, 0, len(env)+2)
+	toolchain := false
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GOROOT=") {
+			continue
+		}
+		if strings.HasPrefix(kv, "GOTOOLCHAIN=") {
+			toolchain = true
+		}
+		res = append(res, kv)
+	}
+	res = append(res, "GOROOT="+goroot)
+	if !toolchain {
+		res = append(res, "GOTOOLCHAIN=local")
+	}
+	return res
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package pkg
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// ExecToolchain replaces the running process with the binary at path, it only returns on failure
+func ExecToolchain(path string, args []string, env []string) (int, error) {
+	err := syscall.Exec(path, append([]string{path}, args...), env)
+	return 1, errors.Wrapf(err, "failed to run %s", path)
+}
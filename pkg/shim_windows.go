@@ -0,0 +1,29 @@
+//go:build windows
+
+package pkg
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/pkg/errors"
+)
+
+// ExecToolchain runs the binary at path and returns its exit code, Windows can't replace the running process
+func ExecToolchain(path string, args []string, env []string) (int, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = env
+	// the child gets console interrupts itself, don't die before it does
+	signal.Ignore(os.Interrupt)
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, errors.Wrapf(err, "failed to run %s", path)
+	}
+	return 0, nil
+}
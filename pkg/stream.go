@@ -0,0 +1,207 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+	"github.com/x0f5c3/go-manager/pkg/progress"
+	"github.com/x0f5c3/manic-go/pkg/downloader"
+	"github.com/x0f5c3/zerolog/log"
+)
+
+// streamClient returns a net/http client connecting like client, since fasthttp can't stream response bodies
+func streamClient(client *downloader.Client) *http.Client {
+	dial := client.Dial
+	if dial == nil {
+		dial = fasthttp.Dial
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialContext(ctx, dial, addr)
+			},
+			ForceAttemptHTTP2: true,
+		},
+	}
+}
+
+// dialContext connects to addr with dial, which takes no context, giving up once ctx is done.
+// A connection made after that is closed.
+func dialContext(ctx context.Context, dial fasthttp.DialFunc, addr string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type dialed struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan dialed, 1)
+	go func() {
+		conn, err := dial(addr)
+		done <- dialed{conn, err}
+	}()
+	select {
+	case d := <-done:
+		return d.conn, d.err
+	case <-ctx.Done():
+		go func() {
+			if d := <-done; d.conn != nil {
+				if err := d.conn.Close(); err != nil {
+					log.Debug().Err(err).Msgf("failed to close the abandoned connection to %s", addr)
+				}
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// CanStream reports whether the file can be extracted while it's downloaded, zip archives can't
+func (f *File) CanStream() bool {
+	return strings.HasSuffix(f.Filename, ".tar.gz") || strings.HasSuffix(f.Filename, ".tgz")
+}
+
+type progressReader struct {
+	r    io.Reader
+	task progress.Task
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.task.Add(int64(n))
+	return n, err
+}
+
+// StreamExtractContext downloads the archive and extracts it into dest in a single pass.
+// The archive is hashed while it's extracted and dest only appears once the checksum is verified.
+// With a Cache in the settings the archive is also written to it and added once verified, otherwise it's never
+// saved to disk. If ctx is done before it completes a CancelledError is returned and nothing is left at dest.
+func (f *File) StreamExtractContext(ctx context.Context, dest string, settings ...*DownloadSettings) error {
+	if !f.CanStream() {
+		return errors.Wrapf(ErrUnknownArchive, "%s can't be streamed", f.Filename)
+	}
+	url := f.URL()
+	client := defaultClient
+	var cache *DownloadCache
+	var reporter progress.Reporter
+	if len(settings) > 0 {
+		url = f.MirrorURL(settings[0].Mirror)
+		if settings[0].Client != nil {
+			client = settings[0].Client
+		}
+		cache = settings[0].Cache
+		reporter = settings[0].Progress
+	}
+	cancelled := func(err error) error {
+		if ctx.Err() != nil {
+			return &CancelledError{URL: url, Err: ctx.Err()}
+		}
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := streamClient(client).Do(req)
+	if err != nil {
+		return cancelled(err)
+	}
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to close the response body of %s", url)
+		}
+	}(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	task := progress.Or(reporter).Start(progress.OpDownload, "Installing "+f.Filename, int64(f.Size))
+	h := sha256.New()
+	var w io.Writer = h
+	archive := cacheFile(cache, f)
+	if archive != nil {
+		defer archive.discard()
+		w = io.MultiWriter(h, archive.file)
+	}
+	body := io.TeeReader(&progressReader{r: resp.Body, task: task}, w)
+	err = extractAtomic(dest, func(dir string) error {
+		if err := extractTarGz(body, dir); err != nil {
+			return err
+		}
+		// the tar reader stops at the end of the archive, hash whatever follows it too
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return err
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); f.Sha256 != "" && got != f.Sha256 {
+			return errors.Wrapf(ErrChecksumMismatch, "%s: expected %s, got %s", url, f.Sha256, got)
+		}
+		return nil
+	})
+	if err != nil {
+		err = cancelled(err)
+		task.Fail(err)
+		return err
+	}
+	task.Done("")
+	if archive != nil {
+		archive.put(cache, f.Sha256)
+	}
+	return nil
+}
+
+// streamedArchive is the copy of a streamed archive that's added to the download cache once it's verified
+type streamedArchive struct {
+	dir  string
+	file *os.File
+}
+
+// cacheFile creates the file the streamed archive f is copied to for cache,
+// it returns nil if there's no cache, the archive can't be verified or the file can't be created
+func cacheFile(cache *DownloadCache, f *File) *streamedArchive {
+	if cache == nil || f.Sha256 == "" || cache.Has(f.Sha256) {
+		return nil
+	}
+	if err := os.MkdirAll(cache.Dir, 0755); err != nil {
+		log.Warn().Err(err).Msg("not adding the streamed archive to the download cache")
+		return nil
+	}
+	// the directory isn't named after a checksum, so it's never taken for a cache entry
+	dir, err := os.MkdirTemp(cache.Dir, "."+f.Sha256+".stream-")
+	if err != nil {
+		log.Warn().Err(err).Msg("not adding the streamed archive to the download cache")
+		return nil
+	}
+	file, err := os.Create(filepath.Join(dir, f.Filename))
+	if err != nil {
+		removeTemp(dir)
+		log.Warn().Err(err).Msg("not adding the streamed archive to the download cache")
+		return nil
+	}
+	return &streamedArchive{dir: dir, file: file}
+}
+
+func (a *streamedArchive) put(cache *DownloadCache, sha string) {
+	if err := a.file.Close(); err != nil {
+		log.Warn().Err(err).Msg("failed to add the archive to the download cache")
+		return
+	}
+	if err := cache.Put(a.file.Name(), sha); err != nil {
+		log.Warn().Err(err).Msg("failed to add the archive to the download cache")
+	}
+}
+
+// discard removes the copy, once it's added to the cache or the stream failed
+func (a *streamedArchive) discard() {
+	if err := a.file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		log.Debug().Err(err).Msgf("failed to close %s", a.file.Name())
+	}
+	removeTemp(a.dir)
+}
@@ -0,0 +1,163 @@
+package pkg
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// assertEmptyDir fails if dir has anything left in it
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("%s left behind in %s", e.Name(), dir)
+	}
+}
+
+func TestStreamExtract(t *testing.T) {
+	archive := makeTarGz(t, []tarEntry{
+		{name: "go/", typeflag: tar.TypeDir},
+		{name: "go/VERSION", typeflag: tar.TypeReg, body: "go1.21.5\n"},
+	})
+	sum := sha256.Sum256(archive)
+	sha := hex.EncodeToString(sum[:])
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+	tests := []struct {
+		name    string
+		sha     string
+		cached  bool
+		wantErr error
+	}{
+		{name: "verified", sha: sha},
+		{name: "cached", sha: sha, cached: true},
+		{name: "checksum mismatch", sha: hex.EncodeToString(make([]byte, sha256.Size)), cached: true, wantErr: ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envs := t.TempDir()
+			dest := filepath.Join(envs, "go1.21.5")
+			f := &File{Filename: "go1.21.5.linux-amd64.tar.gz", Sha256: tt.sha, Size: len(archive)}
+			settings := &DownloadSettings{Mirror: srv.URL}
+			if tt.cached {
+				settings.Cache = NewDownloadCache(t.TempDir(), 0)
+			}
+			err := f.StreamExtractContext(context.Background(), dest, settings)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("StreamExtractContext() error = %v, want %v", err, tt.wantErr)
+				}
+				// neither the toolchain nor its temp dir are left behind
+				assertEmptyDir(t, envs)
+				if settings.Cache != nil {
+					assertEmptyDir(t, settings.Cache.Dir)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b, err := os.ReadFile(filepath.Join(dest, "VERSION")); err != nil || string(b) != "go1.21.5\n" {
+				t.Errorf("VERSION = %q, %v", b, err)
+			}
+			if entries, _ := os.ReadDir(envs); len(entries) != 1 {
+				t.Errorf("%s has %d entries, want only the toolchain", envs, len(entries))
+			}
+			if settings.Cache == nil {
+				return
+			}
+			entries, err := settings.Cache.Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Sha256 != sha || entries[0].Filename != f.Filename {
+				t.Fatalf("cache entries = %+v, want the streamed archive", entries)
+			}
+			if err := verifySha256(entries[0].Path, sha); err != nil {
+				t.Error(err)
+			}
+			// only the entry is left, not the copy it was made from
+			if dirs, _ := os.ReadDir(settings.Cache.Dir); len(dirs) != 1 {
+				t.Errorf("%s has %d entries, want only the cached archive", settings.Cache.Dir, len(dirs))
+			}
+		})
+	}
+}
+
+func TestStreamExtractCancelled(t *testing.T) {
+	archive := makeTarGz(t, []tarEntry{
+		{name: "go/", typeflag: tar.TypeDir},
+		{name: "go/VERSION", typeflag: tar.TypeReg, body: "go1.21.5\n"},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000000")
+		_, _ = w.Write(archive[:len(archive)/2])
+		w.(http.Flusher).Flush()
+		// the rest never comes, the download is cancelled in the middle of the archive
+		cancel()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	envs := t.TempDir()
+	cache := NewDownloadCache(t.TempDir(), 0)
+	f := &File{Filename: "go1.21.5.linux-amd64.tar.gz", Sha256: "sum"}
+	done := make(chan error, 1)
+	go func() {
+		done <- f.StreamExtractContext(ctx, filepath.Join(envs, "go1.21.5"), &DownloadSettings{Mirror: srv.URL, Cache: cache})
+	}()
+	select {
+	case err := <-done:
+		if !IsCancelled(err) || !errors.Is(err, context.Canceled) {
+			t.Errorf("StreamExtractContext() error = %v, want a CancelledError", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StreamExtractContext() didn't return after the context was cancelled")
+	}
+	assertEmptyDir(t, envs)
+	assertEmptyDir(t, cache.Dir)
+}
+
+func TestDialContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	release := make(chan struct{})
+	dial := func(addr string) (net.Conn, error) {
+		<-release
+		return client, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := dialContext(ctx, dial, "example.com:443"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("dialContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	close(release)
+	// the connection made after giving up is closed
+	if err := server.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("abandoned connection read error = %v, want it closed", err)
+	}
+	conn, err := dialContext(context.Background(), func(addr string) (net.Conn, error) { return server, nil }, "example.com:443")
+	if err != nil || conn != server {
+		t.Errorf("dialContext() = %v, %v, want the dialed connection", conn, err)
+	}
+}